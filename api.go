@@ -0,0 +1,36 @@
+package roll
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// apiGet decodes the twitch API response to path into v.  It's for
+// endpoints the twitchapi package doesn't cover.
+func (b *Bot) apiGet(path string, query url.Values, v interface{}) error {
+	u := b.apiClient.UrlBase + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.twitchtv.v5+json")
+	req.Header.Set("Client-ID", b.Config.ClientID)
+	req.Header.Set("Authorization", "OAuth "+b.Config.APIOAuth)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s request returned %s", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -1,15 +1,18 @@
 package roll
 
 import (
+	"context"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/asdine/storm"
 	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/konkers/roll/clock"
 	"github.com/konkers/twitchapi"
 )
 
@@ -20,10 +23,51 @@ type Bot struct {
 	apiClient *twitchapi.Connection
 	commands  *CmdEngine
 
+	// Module that registered each command.  Used to honor per channel
+	// module enablement.
+	ownersMu      sync.Mutex
+	commandOwners map[string]string
+
 	// This should eventually be private and hand out namespaces to modules.
 	db *storm.DB
 
-	modules map[string]Module
+	// Bucket for the bot's own data.  Modules get their own buckets.
+	users       storm.Node
+	userService *UserService
+
+	clock     Clock
+	followers *FollowerCache
+	streams   streamCache
+
+	modules       map[string]Module
+	moduleNames   []string // In the order they were added.
+	loadingModule string   // Set while a module's factory is running.
+	started       []string // Modules that were started, in start order.
+
+	httpsServer *http.Server
+	httpServer  *http.Server
+
+	stateMu   sync.Mutex
+	connState ConnectionState
+	connectC  chan struct{}
+	closeC    chan struct{}
+	ircDone   chan struct{} // Closed when the IRC supervisor exits.
+
+	outQ        *messageQueue
+	limiter     *rateLimiter
+	sendStopC   chan struct{}
+	sendDone    chan struct{}
+	modMu       sync.Mutex
+	modChannels map[string]bool
+
+	// lifecycleMu guards closed and adding to cmdWG so that no commands
+	// are started once Close() has begun waiting for them.
+	lifecycleMu sync.Mutex
+	closed      bool
+	cmdWG       sync.WaitGroup
+
+	subMu       sync.Mutex
+	subscribers []EventSubscriber
 
 	funcMap template.FuncMap
 
@@ -40,6 +84,10 @@ type CommandContext struct {
 
 	UserLevel int
 
+	// Prefix the command was run with.  Use it when telling users about
+	// other commands.
+	Prefix string
+
 	API *twitchapi.Connection
 	IRC *twitch.Client
 }
@@ -70,6 +118,16 @@ type ModuleFactory func(bot *Bot, dbBucket storm.Node) (Module, error)
 
 var moduleFactories = make(map[string]ModuleFactory)
 
+// shutdownTimeout bounds how long Close() waits for web requests to finish.
+const shutdownTimeout = 5 * time.Second
+
+// sendFlushTimeout bounds how long Close() waits for queued messages to be
+// sent.
+const sendFlushTimeout = 5 * time.Second
+
+// coreBucket is the storm bucket reserved for the bot's own data.
+const coreBucket = "_roll"
+
 func RegisterModuleFactory(f ModuleFactory, name string) error {
 	if _, ok := moduleFactories[name]; ok {
 		return fmt.Errorf("Module name \"%s\" registered more than once.", name)
@@ -80,23 +138,61 @@ func RegisterModuleFactory(f ModuleFactory, name string) error {
 
 // NewBot creates a new, unconnected bot.
 func NewBot(config *Config) (*Bot, error) {
+	return NewBotWithClock(config, clock.Real{})
+}
+
+// NewBotWithClock creates a bot that tells time with c.  Tests use it to
+// control the bot's and its modules' timers.
+func NewBotWithClock(config *Config, c Clock) (*Bot, error) {
 	if config.DBPath == "" {
 		config.DBPath = "bot.db"
 	}
+	if config.IRCConnectTimeout.Duration == 0 {
+		config.IRCConnectTimeout.Duration = defaultIRCConnectTimeout
+	}
+	if config.IRCReconnectMin.Duration == 0 {
+		config.IRCReconnectMin.Duration = defaultIRCReconnectMin
+	}
+	if config.IRCReconnectMax.Duration == 0 {
+		config.IRCReconnectMax.Duration = defaultIRCReconnectMax
+	}
+	// IRC channel names are always lower case.
+	for i := range config.Channels {
+		config.Channels[i].Name = strings.ToLower(config.Channels[i].Name)
+	}
+	config.Channel = strings.ToLower(config.Channel)
+
 	db, err := storm.Open(config.DBPath)
 	if err != nil {
 		return nil, fmt.Errorf("can't open storm db: %v", err)
 	}
 
 	b := &Bot{
-		Config:    config,
-		db:        db,
-		modules:   make(map[string]Module),
-		ircClient: twitch.NewClient(config.BotUsername, "oauth:"+config.IRCOAuth),
-		apiClient: twitchapi.NewConnection(config.ClientID, config.APIOAuth),
-		commands:  NewCmdEngine(),
-		funcMap:   make(template.FuncMap),
+		Config:        config,
+		db:            db,
+		clock:         c,
+		modules:       make(map[string]Module),
+		ircClient:     twitch.NewClient(config.BotUsername, "oauth:"+config.IRCOAuth),
+		apiClient:     twitchapi.NewConnection(config.ClientID, config.APIOAuth),
+		commands:      NewCmdEngine(),
+		commandOwners: make(map[string]string),
+		funcMap:       make(template.FuncMap),
+		connectC:      make(chan struct{}, 1),
+		closeC:        make(chan struct{}),
+		outQ:          newMessageQueue(),
+		limiter:       &rateLimiter{window: rateWindow},
+		sendStopC:     make(chan struct{}),
+		sendDone:      make(chan struct{}),
+		modChannels:   make(map[string]bool),
 	}
+	b.commands.Filter = b.commandEnabled
+	b.addBuiltinCommands()
+	b.AddTemplateFunc("userLevel", UserLevelName)
+	b.users = db.From(coreBucket, "users")
+	b.userService = NewUserService(b)
+	b.migrateUserLevels()
+	b.followers = newFollowerCache(b.latestFollowers, b.fetchFollowers, c)
+	b.streams.statuses = make(map[string]streamStatus)
 
 	if config.IRCAddress != "" {
 		b.ircClient.IrcAddress = config.IRCAddress
@@ -105,9 +201,18 @@ func NewBot(config *Config) (*Bot, error) {
 		b.apiClient.UrlBase = config.APIURLBase
 	}
 
+	b.ircClient.OnConnect(b.handleConnect)
 	b.ircClient.OnNewMessage(b.handleMessage)
+	b.ircClient.OnNewUserstateMessage(b.handleUserstate)
+	b.ircClient.OnNewUsernoticeMessage(b.handleUsernotice)
+	b.ircClient.OnNewClearchatMessage(b.handleClearchat)
+	b.ircClient.OnNewRoomstateMessage(b.handleRoomstate)
+	b.ircClient.OnUserJoin(b.handleJoin)
+	b.ircClient.OnUserPart(b.handlePart)
+
+	b.joinChannels()
 
-	b.ircClient.Join(b.Config.Channel)
+	go b.sendWorker()
 
 	return b, nil
 }
@@ -122,96 +227,291 @@ func (b *Bot) AddModuleByName(modType string, name string) error {
 		return fmt.Errorf("Module type %s not found.", modType)
 	}
 
+	if name == coreBucket {
+		return fmt.Errorf("Module name %s is reserved.", name)
+	}
+
 	if _, ok = b.modules[name]; ok {
 		return fmt.Errorf("Module named %s already registered.", name)
 	}
 
+	b.loadingModule = name
 	module, err := factory(b, b.db.From(name))
+	b.loadingModule = ""
 	if err != nil {
 		return fmt.Errorf("Can't instantiate module %s: %v", modType, err)
 	}
 
 	b.modules[name] = module
+	b.moduleNames = append(b.moduleNames, name)
 
 	return nil
 }
 
-// AddCommand adds a bot command.
+// GetModule returns the module added under name.  Modules use this to call
+// each other's APIs.
+func (b *Bot) GetModule(name string) (Module, bool) {
+	m, ok := b.modules[name]
+	return m, ok
+}
+
+// AddCommand adds a bot command.  Commands added from a module's factory
+// are owned by that module and only run in channels it's enabled in.
 func (b *Bot) AddCommand(name string, help string,
 	handler func(*CommandContext, []string) error,
 	userLevel int) error {
-	return b.commands.AddCommand(name, help, handler, userLevel)
+	return b.addCommand(b.loadingModule, name, help, handler, userLevel, Cooldown{})
+}
+
+// AddCommandWithCooldown adds a bot command that can only be run as often
+// as cooldown allows.
+func (b *Bot) AddCommandWithCooldown(name string, help string,
+	handler func(*CommandContext, []string) error,
+	userLevel int, cooldown Cooldown) error {
+	return b.addCommand(b.loadingModule, name, help, handler, userLevel, cooldown)
+}
+
+// AddModuleCommand adds a bot command owned by module m.  Modules that add
+// commands outside of their factory use this so the command only runs in
+// channels m is enabled in.
+func (b *Bot) AddModuleCommand(m Module, name string, help string,
+	handler func(*CommandContext, []string) error,
+	userLevel int) error {
+	return b.addCommand(b.moduleName(m), name, help, handler, userLevel, Cooldown{})
+}
+
+func (b *Bot) addCommand(owner string, name string, help string,
+	handler func(*CommandContext, []string) error,
+	userLevel int, cooldown Cooldown) error {
+	err := b.commands.AddCommandWithCooldown(name, help, handler, userLevel, cooldown)
+	if err != nil {
+		return err
+	}
+	b.ownersMu.Lock()
+	b.commandOwners[name] = owner
+	b.ownersMu.Unlock()
+	return nil
+}
+
+// AddSubEngine lists the commands of sub under command name in help.
+func (b *Bot) AddSubEngine(name string, sub *CmdEngine) error {
+	return b.commands.AddSubEngine(name, sub)
 }
 
 // RemoveCommand removes a bot command.
 func (b *Bot) RemoveCommand(name string) error {
+	b.ownersMu.Lock()
+	delete(b.commandOwners, name)
+	b.ownersMu.Unlock()
 	return b.commands.RemoveCommand(name)
 }
 
+func (b *Bot) commandOwner(name string) string {
+	b.ownersMu.Lock()
+	defer b.ownersMu.Unlock()
+	return b.commandOwners[name]
+}
+
 // Connect the bot to Twitch.
 func (b *Bot) Connect() error {
-	// TODO: stop webserver on error
 	err := b.startWebserver()
 	if err != nil {
+		b.stopWebserver(context.Background())
 		return err
 	}
 
-	errChan := make(chan error)
-	connectChan := make(chan bool)
-	b.ircClient.OnConnect(func() {
-		connectChan <- true
-	})
-	go func() {
-		err := b.ircClient.Connect()
-		errChan <- err
-	}()
+	errC, err := b.dialIRC()
+	if err != nil {
+		b.stopWebserver(context.Background())
+		return err
+	}
+	b.ircDone = make(chan struct{})
+	go b.superviseIRC(errC)
+	go b.followerWorker()
 
-	select {
-	case err := <-errChan:
+	for _, name := range b.moduleNames {
+		err := b.modules[name].Start()
 		if err != nil {
-			return fmt.Errorf("Can't connect to irc: %v", err)
+			log.Printf("Can't start module %s: %v", name, err)
+			continue
 		}
-	case <-time.After(time.Second * 3):
-		return fmt.Errorf("IRC connect timed out")
-	case <-connectChan:
-		// success case
+		b.started = append(b.started, name)
 	}
 
-	for _, mod := range b.modules {
-		mod.Start()
+	return nil
+}
+
+// Run connects the bot and serves until ctx is done, at which point the
+// bot is closed.
+func (b *Bot) Run(ctx context.Context) error {
+	err := b.Connect()
+	if err != nil {
+		b.Close()
+		return err
 	}
 
-	return nil
+	<-ctx.Done()
+	log.Printf("Shutting down: %v", ctx.Err())
+	return b.Close()
 }
 
-func (b *Bot) handleMessage(channel string, user twitch.User, message twitch.Message) {
-	log.Println(channel)
-	log.Println()
-	if strings.HasPrefix(message.Text, "!") {
-		ctx := &CommandContext{
-			Bot:       b,
-			Channel:   channel,
-			User:      &user,
-			UserLevel: b.userLevel(user.Username),
-			Message:   &message,
-			API:       b.apiClient,
-			IRC:       b.ircClient,
-		}
-		b.cmdErr = b.commands.ExecString(
-			ctx, b.userLevel(user.Username),
-			strings.TrimPrefix(message.Text, "!"))
-		if b.cmdErr != nil {
-			log.Printf("Can't exec \"%s\": %v.", message.Text, b.cmdErr)
+// Close shuts the bot down.  New commands are refused and in-flight ones
+// are allowed to finish before modules are stopped in the reverse order
+// they were started.  Finally the web servers, IRC connection, and
+// database are closed.  Calling Close more than once is a no-op.
+func (b *Bot) Close() error {
+	b.lifecycleMu.Lock()
+	if b.closed {
+		b.lifecycleMu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.closeC)
+	b.lifecycleMu.Unlock()
+
+	b.cmdWG.Wait()
+
+	for i := len(b.started) - 1; i >= 0; i-- {
+		name := b.started[i]
+		err := b.modules[name].Stop()
+		if err != nil {
+			log.Printf("Error stopping module %s: %v", name, err)
 		}
 	}
+	b.started = nil
+
+	// Replies to the commands that were running and anything said while
+	// stopping modules are still queued.
+	close(b.sendStopC)
+	<-b.sendDone
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	b.stopWebserver(ctx)
+
+	// Disconnect returns an error if we never connected.  That's fine.
+	b.ircClient.Disconnect()
+	if b.ircDone != nil {
+		<-b.ircDone
+	}
+	b.setConnectionState(ConnectionDisconnected)
+
+	return b.db.Close()
+}
+
+// beginCommand registers an in-flight command.  It returns false if the
+// bot is shutting down and the command should be dropped.
+func (b *Bot) beginCommand() bool {
+	b.lifecycleMu.Lock()
+	defer b.lifecycleMu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.cmdWG.Add(1)
+	return true
+}
+
+func (b *Bot) handleMessage(channel string, user twitch.User, message twitch.Message) {
+	if b.channelConfig(channel) == nil {
+		log.Printf("Ignoring message from unknown channel %s.", channel)
+		return
+	}
+
+	if !b.beginCommand() {
+		return
+	}
+	defer b.cmdWG.Done()
+
+	isCommand := b.handleCommand(channel, &user, &message)
+
+	b.publish(&Event{
+		Type:     EventMessage,
+		Channel:  channel,
+		Username: user.Username,
+		User:     &user,
+		Message:  &message,
+		Command:  isCommand,
+	})
+}
+
+// handleCommand runs the command in message, if any.  It returns true if
+// the message was a command.
+func (b *Bot) handleCommand(channel string, user *twitch.User, message *twitch.Message) bool {
+	userLevel := b.userLevel(channel, user)
+	ctx := &CommandContext{
+		Bot:       b,
+		Channel:   channel,
+		User:      user,
+		UserLevel: userLevel,
+		Message:   message,
+		API:       b.apiClient,
+		IRC:       b.ircClient,
+	}
+	var isCommand bool
+	isCommand, b.cmdErr = b.commands.ExecMessage(ctx, b.commandParser(channel), message.Text)
+	if b.cmdErr != nil {
+		log.Printf("Can't exec \"%s\": %v.", message.Text, b.cmdErr)
+	}
+	return isCommand
+}
+
+// RunCommand runs line, a command without its prefix, in channel as the
+// bot at broadcaster level.  Scheduled actions use it.
+func (b *Bot) RunCommand(channel string, line string) error {
+	return b.RunCommandAs(channel, line, UserLevelBroadcaster)
 }
 
-func (b *Bot) userLevel(username string) int {
-	if username == b.Config.AdminUser {
-		return 100
-	} else {
-		return 0
+// RunCommandAs runs line like RunCommand, but at userLevel.  Actions set
+// off by a user run at the user's level so they can't be used to run
+// commands the user can't.
+func (b *Bot) RunCommandAs(channel string, line string, userLevel int) error {
+	if !b.beginCommand() {
+		return fmt.Errorf("bot is shutting down")
 	}
+	defer b.cmdWG.Done()
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return fmt.Errorf("no command given")
+	}
+
+	cc := &CommandContext{
+		Bot:     b,
+		Channel: channel,
+		User: &twitch.User{
+			Username:    strings.ToLower(b.Config.BotUsername),
+			DisplayName: b.Config.BotUsername,
+		},
+		UserLevel: userLevel,
+		Message: &twitch.Message{
+			Channel: channel,
+			Text:    line,
+		},
+		Prefix: b.CommandPrefix(channel),
+		API:    b.apiClient,
+		IRC:    b.ircClient,
+	}
+	if !b.commandEnabled(cc, fields[0]) {
+		return fmt.Errorf("%s isn't enabled in %s", fields[0], channel)
+	}
+	return b.commands.ExecString(cc, cc.UserLevel, line)
+}
+
+// commandParser returns the parser for commands in channel.
+func (b *Bot) commandParser(channel string) *CommandParser {
+	parser := &CommandParser{
+		Mention: b.Config.BotUsername,
+	}
+	if c := b.channelConfig(channel); c != nil {
+		parser.Prefixes = c.Prefixes
+	}
+	return parser
+}
+
+// commandEnabled filters out commands owned by modules that aren't enabled
+// in the channel.
+func (b *Bot) commandEnabled(cc *CommandContext, name string) bool {
+	return b.moduleEnabled(cc.Channel, b.commandOwner(name))
 }
 
 func (b *Bot) IsAdminRequest(r *http.Request) bool {
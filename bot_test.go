@@ -1,14 +1,19 @@
 package roll
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/asdine/storm"
+	twitch "github.com/gempir/go-twitch-irc"
 	"github.com/konkers/mocktwitch"
+	"github.com/konkers/roll/clocktest"
 	"github.com/phayes/freeport"
 )
 
@@ -52,7 +57,8 @@ func newTestBot(t *testing.T) (*Bot, *mocktwitch.Twitch) {
 		DBPath:      dbPath,
 	}
 
-	b, err := NewBot(testConfig)
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	b, err := NewBotWithClock(testConfig, clock)
 	if b == nil {
 		t.Fatalf("NewBot() returned nil")
 	}
@@ -111,6 +117,11 @@ func TestNewBotDefaults(t *testing.T) {
 	if bot.Config.DBPath != "bot.db" {
 		t.Errorf("DBPath default %s is not the expected bot.db.", bot.Config.DBPath)
 	}
+
+	if bot.Config.IRCConnectTimeout.Duration != defaultIRCConnectTimeout {
+		t.Errorf("IRCConnectTimeout default %v is not the expected %v.",
+			bot.Config.IRCConnectTimeout, defaultIRCConnectTimeout)
+	}
 }
 
 func TestNewBotBadDbPath(t *testing.T) {
@@ -184,15 +195,54 @@ func TestBotFailedCommand(t *testing.T) {
 	}
 }
 
+func TestRunCommand(t *testing.T) {
+	b, _ := newTestBot(t)
+
+	var level int
+	var args []string
+	b.AddCommand("sched", "test", func(cc *CommandContext, a []string) error {
+		level = cc.UserLevel
+		args = a
+		return nil
+	}, UserLevelModerator)
+
+	err := b.RunCommand("testchan", "sched a b")
+	if err != nil {
+		t.Fatalf("RunCommand() returned error: %v", err)
+	}
+	if level != UserLevelBroadcaster {
+		t.Errorf("RunCommand() ran at level %d", level)
+	}
+	if !reflect.DeepEqual(args, []string{"a", "b"}) {
+		t.Errorf("RunCommand() passed args %v", args)
+	}
+
+	err = b.RunCommand("testchan", "")
+	if err == nil {
+		t.Errorf("RunCommand() didn't return error for empty command")
+	}
+
+	level = -1
+	err = b.RunCommandAs("testchan", "sched", UserLevelModerator)
+	if err != nil || level != UserLevelModerator {
+		t.Errorf("RunCommandAs() at moderator level ran at level %d: %v", level, err)
+	}
+	level = -1
+	b.RunCommandAs("testchan", "sched", UserLevelVIP)
+	if level != -1 {
+		t.Errorf("RunCommandAs() ran a moderator command at VIP level")
+	}
+}
+
 func TestUserLevel(t *testing.T) {
 	b, _ := newTestBot(t)
 
-	adminUserLevel := b.userLevel(b.Config.AdminUser)
+	adminUserLevel := b.userLevel("testchan", &twitch.User{Username: b.Config.AdminUser})
 	if adminUserLevel != 100 {
 		t.Errorf("Admin user level(%d) != 100", adminUserLevel)
 	}
 
-	normalUserLevel := b.userLevel("nobody")
+	normalUserLevel := b.userLevel("testchan", &twitch.User{Username: "nobody"})
 	if normalUserLevel != 0 {
 		t.Errorf("Admin user level(%d) != 0", normalUserLevel)
 	}
@@ -237,3 +287,87 @@ func TestAccessors(t *testing.T) {
 		t.Errorf("Irc() accessor did not return ircClient")
 	}
 }
+
+type orderTestModule struct {
+	name   string
+	events *[]string
+}
+
+func (m *orderTestModule) Start() error {
+	*m.events = append(*m.events, "start "+m.name)
+	return nil
+}
+
+func (m *orderTestModule) Stop() error {
+	*m.events = append(*m.events, "stop "+m.name)
+	return nil
+}
+
+func TestBotClose(t *testing.T) {
+	var events []string
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		factory := func(bot *Bot, db storm.Node) (Module, error) {
+			return &orderTestModule{name: name, events: &events}, nil
+		}
+		err := RegisterModuleFactory(factory, "order_"+name)
+		if err != nil {
+			t.Fatalf("Unexpected error from RegisterModuleFactory(): %v", err)
+		}
+	}
+
+	b, mock := newTestBot(t)
+	for _, name := range []string{"a", "b", "c"} {
+		err := b.AddModule("order_" + name)
+		if err != nil {
+			t.Fatalf("Unexpected error from AddModule(): %v", err)
+		}
+	}
+	connectTestBot(t, b, mock)
+
+	err := b.Close()
+	if err != nil {
+		t.Errorf("Unexpected error from Close(): %v", err)
+	}
+
+	expected := []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Module lifecycle events %v != %v", events, expected)
+	}
+
+	client := getTestHttpClient()
+	url := "https://" + b.Config.HTTPSAddr + "/"
+	_, err = client.Get(url)
+	if err == nil {
+		t.Errorf("Web server still serving after Close()")
+	}
+
+	err = b.Close()
+	if err != nil {
+		t.Errorf("Unexpected error from second Close(): %v", err)
+	}
+
+	if b.beginCommand() {
+		t.Errorf("Command accepted after Close()")
+	}
+}
+
+func TestBotRun(t *testing.T) {
+	b, _ := newTestBot(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error from Run(): %v", err)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("Run() did not return after context was canceled")
+	}
+}
@@ -0,0 +1,82 @@
+package roll
+
+import (
+	"strings"
+)
+
+// Channels returns the names of the channels the bot serves.
+func (b *Bot) Channels() []string {
+	var channels []string
+	for _, c := range b.Config.channelConfigs() {
+		channels = append(channels, c.Name)
+	}
+	return channels
+}
+
+// DefaultChannel returns the first configured channel.  Data saved before
+// the bot supported multiple channels belongs to it.
+func (b *Bot) DefaultChannel() string {
+	channels := b.Channels()
+	if len(channels) == 0 {
+		return ""
+	}
+	return channels[0]
+}
+
+// CommandPrefix returns the prefix to show users when telling them about
+// commands in channel outside of a command.
+func (b *Bot) CommandPrefix(channel string) string {
+	if c := b.channelConfig(channel); c != nil && len(c.Prefixes) > 0 {
+		return c.Prefixes[0]
+	}
+	return DefaultPrefix
+}
+
+func (b *Bot) channelConfig(channel string) *ChannelConfig {
+	for _, c := range b.Config.channelConfigs() {
+		if strings.EqualFold(c.Name, channel) {
+			c := c
+			return &c
+		}
+	}
+	return nil
+}
+
+// ModuleEnabled reports whether module m is enabled in channel.
+func (b *Bot) ModuleEnabled(channel string, m Module) bool {
+	return b.moduleEnabled(channel, b.moduleName(m))
+}
+
+func (b *Bot) moduleEnabled(channel string, name string) bool {
+	c := b.channelConfig(channel)
+	if c == nil {
+		return false
+	}
+	if name == "" || len(c.Modules) == 0 {
+		return true
+	}
+	for _, mod := range c.Modules {
+		if mod == name {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleName returns the name m was added under.  While a module's factory
+// is running it is not yet registered, so the name of the module being
+// loaded is returned instead.
+func (b *Bot) moduleName(m Module) string {
+	for name, mod := range b.modules {
+		if mod == m {
+			return name
+		}
+	}
+	return b.loadingModule
+}
+
+func (b *Bot) joinChannels() {
+	for _, channel := range b.Channels() {
+		b.ircClient.Join(channel)
+	}
+}
@@ -0,0 +1,107 @@
+package roll
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/asdine/storm"
+)
+
+func TestChannels(t *testing.T) {
+	b, _ := newTestBot(t)
+
+	if !reflect.DeepEqual(b.Channels(), []string{"testchan"}) {
+		t.Errorf("Single channel config gives channels %v", b.Channels())
+	}
+
+	b.Config.Channels = []ChannelConfig{
+		{Name: "a"},
+		{Name: "b", Modules: []string{"x"}, Prefixes: []string{"?", "!"}},
+	}
+
+	if !reflect.DeepEqual(b.Channels(), []string{"a", "b"}) {
+		t.Errorf("Channels() returned %v instead of [a b]", b.Channels())
+	}
+
+	if b.DefaultChannel() != "a" {
+		t.Errorf("DefaultChannel() returned %s instead of a", b.DefaultChannel())
+	}
+
+	if b.CommandPrefix("a") != DefaultPrefix || b.CommandPrefix("b") != "?" {
+		t.Errorf("CommandPrefix() returned %s and %s instead of ! and ?",
+			b.CommandPrefix("a"), b.CommandPrefix("b"))
+	}
+
+	tests := []struct {
+		channel string
+		module  string
+		enabled bool
+	}{
+		{"a", "x", true},
+		{"a", "y", true},
+		{"b", "x", true},
+		{"b", "y", false},
+		{"b", "", true},
+		{"c", "x", false},
+	}
+	for _, test := range tests {
+		enabled := b.moduleEnabled(test.channel, test.module)
+		if enabled != test.enabled {
+			t.Errorf("moduleEnabled(%s, %s) returned %v", test.channel, test.module, enabled)
+		}
+	}
+}
+
+type ownerTestModule struct {
+	bot *Bot
+}
+
+func (m *ownerTestModule) Start() error {
+	return nil
+}
+
+func (m *ownerTestModule) Stop() error {
+	return nil
+}
+
+func TestCommandOwner(t *testing.T) {
+	var module *ownerTestModule
+	err := RegisterModuleFactory(func(bot *Bot, db storm.Node) (Module, error) {
+		module = &ownerTestModule{bot: bot}
+		bot.AddCommand("factorycmd", "test", func(cc *CommandContext, args []string) error {
+			return nil
+		}, 0)
+		return module, nil
+	}, "owner_test")
+	if err != nil {
+		t.Fatalf("Unexpected error from RegisterModuleFactory(): %v", err)
+	}
+
+	b, _ := newTestBot(t)
+	err = b.AddModule("owner_test")
+	if err != nil {
+		t.Fatalf("Unexpected error from AddModule(): %v", err)
+	}
+
+	b.AddModuleCommand(module, "latecmd", "test", func(cc *CommandContext, args []string) error {
+		return nil
+	}, 0)
+	b.AddCommand("corecmd", "test", func(cc *CommandContext, args []string) error {
+		return nil
+	}, 0)
+
+	for cmd, owner := range map[string]string{
+		"factorycmd": "owner_test",
+		"latecmd":    "owner_test",
+		"corecmd":    "",
+	} {
+		if b.commandOwner(cmd) != owner {
+			t.Errorf("%s is owned by \"%s\" instead of \"%s\"", cmd, b.commandOwner(cmd), owner)
+		}
+	}
+
+	b.RemoveCommand("latecmd")
+	if b.commandOwner("latecmd") != "" {
+		t.Errorf("Removed command still has owner")
+	}
+}
@@ -0,0 +1,18 @@
+package roll
+
+import "github.com/konkers/roll/clock"
+
+// Clock is the bot's source of time.  Modules get the time and make their
+// tickers and timers through Bot.Clock() so tests can control time.
+type Clock = clock.Clock
+
+// Ticker delivers ticks on C() at intervals.
+type Ticker = clock.Ticker
+
+// Timer calls a function once after a delay unless stopped.
+type Timer = clock.Timer
+
+// Clock returns the bot's clock.
+func (b *Bot) Clock() Clock {
+	return b.clock
+}
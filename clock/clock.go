@@ -0,0 +1,56 @@
+// Package clock defines the bot's source of time.  It has no dependencies on
+// the bot so fakes like clocktest can implement it without import cycles.
+package clock
+
+import "time"
+
+// Clock tells the time and makes tickers and timers.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker delivers ticks on C() at intervals.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer calls a function once after a delay unless stopped.
+type Timer interface {
+	Stop() bool
+}
+
+// Real is the Clock that uses the system time.
+type Real struct{}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// After waits for d to pass on c and then sends the time on the returned
+// channel, like time.After.
+func After(c Clock, d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() {
+		ch <- c.Now()
+	})
+	return ch
+}
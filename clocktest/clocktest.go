@@ -0,0 +1,126 @@
+// Package clocktest provides a fake clock for testing code that tells time
+// through a clock.Clock.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/konkers/roll/clock"
+)
+
+// Clock is a clock.Clock whose time only moves when Advance is called.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+type timer struct {
+	clock   *Clock
+	when    time.Time
+	period  time.Duration // Set for tickers.
+	f       func()
+	c       chan time.Time
+	stopped bool
+}
+
+type ticker struct {
+	*timer
+}
+
+// New returns a Clock set to now.
+func New(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker that ticks every d of fake time.
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	t := &timer{period: d, c: make(chan time.Time, 1)}
+	c.add(t, d)
+	return ticker{t}
+}
+
+// AfterFunc calls f once d of fake time has passed.
+func (c *Clock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	t := &timer{f: f}
+	c.add(t, d)
+	return t
+}
+
+func (c *Clock) add(t *timer, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t.clock = c
+	t.when = c.now.Add(d)
+	c.timers = append(c.timers, t)
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that
+// come due in order.  Timer functions run before Advance returns.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *timer
+		for _, t := range c.timers {
+			if !t.stopped && !t.when.After(end) &&
+				(next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		// Timers set for the past fire now, like time.AfterFunc's.
+		if next.when.After(c.now) {
+			c.now = next.when
+		}
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			next.stopped = true
+		}
+		c.mu.Unlock()
+		next.fire(next.clock.Now())
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+func (t *timer) fire(now time.Time) {
+	if t.f != nil {
+		t.f()
+		return
+	}
+	// Like time.Ticker, drop ticks the reader isn't keeping up with.
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := !t.stopped
+	t.stopped = true
+	return active
+}
+
+func (t ticker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t ticker) Stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,61 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(start)
+
+	var fired []string
+	c.AfterFunc(2*time.Minute, func() {
+		fired = append(fired, "b:"+c.Now().Sub(start).String())
+	})
+	c.AfterFunc(time.Minute, func() {
+		fired = append(fired, "a:"+c.Now().Sub(start).String())
+	})
+	stopped := c.AfterFunc(30*time.Second, func() {
+		fired = append(fired, "stopped")
+	})
+	if !stopped.Stop() {
+		t.Errorf("Stop() of pending timer returned false")
+	}
+
+	ticker := c.NewTicker(45 * time.Second)
+	c.Advance(90 * time.Second)
+	if len(fired) != 1 || fired[0] != "a:1m0s" {
+		t.Errorf("After 90s timers fired %v instead of [a:1m0s]", fired)
+	}
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(45 * time.Second)) {
+			t.Errorf("First tick at %v instead of 45s", tick.Sub(start))
+		}
+	default:
+		t.Errorf("Ticker didn't tick")
+	}
+
+	ticker.Stop()
+	var late time.Duration
+	c.AfterFunc(-time.Minute, func() {
+		late = c.Now().Sub(start)
+	})
+	c.Advance(0)
+	if late != 90*time.Second {
+		t.Errorf("Timer set for the past fired at %v instead of 1m30s", late)
+	}
+	c.Advance(time.Hour)
+	if len(fired) != 2 || fired[1] != "b:2m0s" {
+		t.Errorf("After an hour timers fired %v instead of [a:1m0s b:2m0s]", fired)
+	}
+	select {
+	case <-ticker.C():
+		t.Errorf("Stopped ticker ticked")
+	default:
+	}
+	if !c.Now().Equal(start.Add(time.Hour + 90*time.Second)) {
+		t.Errorf("Clock at %v after advancing 1h1m30s", c.Now().Sub(start))
+	}
+}
@@ -2,29 +2,329 @@ package roll
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/konkers/cmd"
 )
 
+// Cooldown limits how often a command can be run.
+type Cooldown struct {
+	// Minimum time between runs by anyone.
+	Global time.Duration
+
+	// Minimum time between runs by the same user.
+	User time.Duration
+
+	// Users at or above this level ignore the cooldown.  Defaults to
+	// UserLevelModerator.
+	BypassLevel int
+
+	// Whisper users how long is left when they hit the cooldown.
+	Notify bool
+}
+
+// DefaultPrefix starts commands in channels that don't configure prefixes.
+const DefaultPrefix = "!"
+
+// CommandParser finds commands in chat messages.
+type CommandParser struct {
+	// Prefixes that start a command.  DefaultPrefix is used if empty.
+	Prefixes []string
+
+	// Commands can also be run by mentioning this name, ex:
+	// "@RollTheRobot game".  Mentions are ignored if empty.
+	Mention string
+}
+
+// CommandInfo describes a command for help listings.
+type CommandInfo struct {
+	// Full name of the command, ex: "marathon next".
+	Name      string
+	Help      string
+	UserLevel int
+}
+
 type CmdEngine struct {
 	*cmd.Engine
+
+	// If set, commands are only run if Filter returns true.
+	Filter func(cc *CommandContext, name string) bool
+
+	mu        sync.Mutex
+	cooldowns map[string]*cooldownState
+	infos     map[string]*commandInfo
+}
+
+type commandInfo struct {
+	help      string
+	userLevel int
+	sub       *CmdEngine
+}
+
+// cooldownState tracks a command's runs separately in each channel.
+type cooldownState struct {
+	Cooldown
+	// Keyed by channel.
+	lastRun map[string]time.Time
+	// Keyed by cooldownUserKey.
+	userLastRun map[string]time.Time
+}
+
+func cooldownUserKey(channel string, username string) string {
+	return channel + "\x00" + username
 }
 
 func NewCmdEngine() *CmdEngine {
 	return &CmdEngine{
-		Engine: cmd.NewEngine(),
+		Engine:    cmd.NewEngine(),
+		cooldowns: make(map[string]*cooldownState),
+		infos:     make(map[string]*commandInfo),
 	}
 }
 
 func (e *CmdEngine) AddCommand(name string, help string,
 	handler func(*CommandContext, []string) error,
 	userLevel int) error {
+	return e.AddCommandWithCooldown(name, help, handler, userLevel, Cooldown{})
+}
+
+// AddCommandWithCooldown adds a command that can only be run as often as
+// cooldown allows.
+func (e *CmdEngine) AddCommandWithCooldown(name string, help string,
+	handler func(*CommandContext, []string) error,
+	userLevel int, cooldown Cooldown) error {
 	proxyHandler := func(ctx interface{}, args []string) error {
 		cc, ok := ctx.(*CommandContext)
 		if !ok {
 			return fmt.Errorf("ctx not a CommandContext")
 		}
+		now := time.Now()
+		if cc.Bot != nil {
+			now = cc.Bot.Clock().Now()
+		}
+		if remaining := e.useCooldown(name, cc, now); remaining > 0 {
+			e.notifyCooldown(name, cc, remaining)
+			return nil
+		}
 		return handler(cc, args)
 	}
-	return e.Engine.AddCommand(name, help, proxyHandler, userLevel)
+	err := e.Engine.AddCommand(name, help, proxyHandler, userLevel)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.infos[name] = &commandInfo{
+		help:      help,
+		userLevel: userLevel,
+	}
+	e.mu.Unlock()
+
+	if cooldown.Global > 0 || cooldown.User > 0 {
+		if cooldown.BypassLevel == 0 {
+			cooldown.BypassLevel = UserLevelModerator
+		}
+		e.mu.Lock()
+		e.cooldowns[name] = &cooldownState{
+			Cooldown:    cooldown,
+			lastRun:     make(map[string]time.Time),
+			userLastRun: make(map[string]time.Time),
+		}
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+// Parse returns the command line in text with the prefix or mention
+// stripped, along with the prefix to use when referring to commands.  ok
+// is false if text isn't a command.
+func (p *CommandParser) Parse(text string) (line string, prefix string, ok bool) {
+	prefixes := p.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{DefaultPrefix}
+	}
+
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(text, prefix) {
+			return strings.TrimPrefix(text, prefix), prefix, true
+		}
+	}
+
+	if p.Mention == "" {
+		return "", "", false
+	}
+	fields := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	name := strings.TrimRight(strings.TrimPrefix(fields[0], "@"), ",:")
+	if len(fields) != 2 || !strings.EqualFold(name, p.Mention) {
+		return "", "", false
+	}
+	return fields[1], prefixes[0], true
+}
+
+// ExecMessage runs the command in a chat message.  It returns true if text
+// was a command.
+func (e *CmdEngine) ExecMessage(cc *CommandContext, parser *CommandParser, text string) (bool, error) {
+	line, prefix, ok := parser.Parse(text)
+	if !ok {
+		return false, nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	if e.Filter != nil && !e.Filter(cc, fields[0]) {
+		return false, nil
+	}
+
+	cc.Prefix = prefix
+	return true, e.ExecString(cc, cc.UserLevel, line)
+}
+
+func (e *CmdEngine) RemoveCommand(name string) error {
+	e.mu.Lock()
+	delete(e.cooldowns, name)
+	delete(e.infos, name)
+	e.mu.Unlock()
+	return e.Engine.RemoveCommand(name)
+}
+
+// AddSubEngine includes the commands of sub under command name in help
+// listings.  The command's handler is still responsible for running sub.
+func (e *CmdEngine) AddSubEngine(name string, sub *CmdEngine) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	info, ok := e.infos[name]
+	if !ok {
+		return fmt.Errorf("no command named %s", name)
+	}
+	info.sub = sub
+	return nil
+}
+
+// Commands returns the commands that can be run at userLevel, including
+// those of sub engines, sorted by name.
+func (e *CmdEngine) Commands(userLevel int) []CommandInfo {
+	return e.commandList(userLevel, "", UserLevelViewer)
+}
+
+func (e *CmdEngine) commandList(userLevel int, parent string, parentLevel int) []CommandInfo {
+	e.mu.Lock()
+	var names []string
+	for name := range e.infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]commandInfo, len(names))
+	for i, name := range names {
+		infos[i] = *e.infos[name]
+	}
+	e.mu.Unlock()
+
+	var cmds []CommandInfo
+	for i, info := range infos {
+		level := info.userLevel
+		if parentLevel > level {
+			level = parentLevel
+		}
+		if level > userLevel {
+			continue
+		}
+
+		name := strings.TrimSpace(parent + " " + names[i])
+		cmds = append(cmds, CommandInfo{
+			Name:      name,
+			Help:      info.help,
+			UserLevel: level,
+		})
+		if info.sub != nil {
+			cmds = append(cmds, info.sub.commandList(userLevel, name, level)...)
+		}
+	}
+	return cmds
+}
+
+// Lookup finds the command named by path, ex: ["marathon", "next"].
+func (e *CmdEngine) Lookup(path []string) (CommandInfo, bool) {
+	engine := e
+	var cmd CommandInfo
+	for _, name := range path {
+		if engine == nil {
+			return CommandInfo{}, false
+		}
+
+		engine.mu.Lock()
+		info, ok := engine.infos[name]
+		var sub *CmdEngine
+		if ok {
+			sub = info.sub
+			cmd.Help = info.help
+			if info.userLevel > cmd.UserLevel {
+				cmd.UserLevel = info.userLevel
+			}
+		}
+		engine.mu.Unlock()
+		if !ok {
+			return CommandInfo{}, false
+		}
+		engine = sub
+	}
+	cmd.Name = strings.Join(path, " ")
+	return cmd, len(path) > 0
+}
+
+// useCooldown checks the cooldown of command name.  If the command may run
+// the run is recorded and 0 is returned.  Otherwise the time left on the
+// cooldown is returned.
+func (e *CmdEngine) useCooldown(name string, cc *CommandContext, now time.Time) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, ok := e.cooldowns[name]
+	if !ok || cc.UserLevel >= state.BypassLevel {
+		return 0
+	}
+
+	username := ""
+	if cc.User != nil {
+		username = cc.User.Username
+	}
+	userKey := cooldownUserKey(cc.Channel, username)
+
+	var remaining time.Duration
+	if last, ok := state.lastRun[cc.Channel]; ok {
+		if d := last.Add(state.Global).Sub(now); d > remaining {
+			remaining = d
+		}
+	}
+	if last, ok := state.userLastRun[userKey]; ok {
+		if d := last.Add(state.User).Sub(now); d > remaining {
+			remaining = d
+		}
+	}
+	if remaining > 0 {
+		return remaining
+	}
+
+	state.lastRun[cc.Channel] = now
+	if state.User > 0 {
+		state.userLastRun[userKey] = now
+	}
+	return 0
+}
+
+func (e *CmdEngine) notifyCooldown(name string, cc *CommandContext, remaining time.Duration) {
+	e.mu.Lock()
+	state, ok := e.cooldowns[name]
+	notify := ok && state.Notify
+	e.mu.Unlock()
+
+	if !notify || cc.Bot == nil || cc.User == nil {
+		return
+	}
+	cc.Whisper(fmt.Sprintf("%s%s is on cooldown for %v.", cc.Prefix, name,
+		remaining.Truncate(time.Second)+time.Second))
 }
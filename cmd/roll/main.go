@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/konkers/mocktwitch"
 	"github.com/konkers/roll"
@@ -11,6 +14,9 @@ import (
 	_ "github.com/konkers/roll/modules/game"
 	_ "github.com/konkers/roll/modules/giveaway"
 	_ "github.com/konkers/roll/modules/marathon"
+	_ "github.com/konkers/roll/modules/points"
+	_ "github.com/konkers/roll/modules/poll"
+	_ "github.com/konkers/roll/modules/quote"
 	_ "github.com/konkers/roll/modules/simplecmd"
 )
 
@@ -18,12 +24,21 @@ var configFileName = flag.String("config", "config.json", "Config file")
 var testServer = flag.Bool("test", false, "Enables mocked twitch server")
 
 func main() {
+	flag.Parse()
 
 	config, err := roll.LoadConfig(*configFileName)
 	if err != nil {
 		log.Fatalf("Can't load Config: %v", err)
 	}
 
+	if flag.NArg() > 0 {
+		err = runMarathonCommand(config, flag.Args())
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	var mock *mocktwitch.Twitch
 	if *testServer {
 		mock, err = mocktwitch.NewTwitch()
@@ -38,19 +53,33 @@ func main() {
 	if err != nil {
 		log.Fatalf("Can't create bot: %v", err)
 	}
-	b.AddModule("alert")
-	b.AddModule("game")
-	b.AddModule("giveaway")
-	b.AddModule("marathon")
-	b.AddModule("simplecmd")
-
-	err = b.Connect()
-	if err != nil {
-		log.Fatalf("Can't connect bot: %v", err)
+	for _, name := range []string{
+		"alert",
+		"game",
+		"giveaway",
+		"marathon",
+		"points",
+		"poll",
+		"quote",
+		"simplecmd",
+	} {
+		err = b.AddModule(name)
+		if err != nil {
+			log.Fatalf("Can't add module: %v", err)
+		}
 	}
 
-	// Hack until proper termination control is done.
-	for {
-		time.Sleep(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigC
+		log.Printf("Got %v.", sig)
+		cancel()
+	}()
+
+	err = b.Run(ctx)
+	if err != nil {
+		log.Fatalf("Bot exited with error: %v", err)
 	}
 }
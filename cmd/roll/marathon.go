@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm"
+	bolt "github.com/coreos/bbolt"
+	"github.com/konkers/roll"
+	"github.com/konkers/roll/modules/marathon"
+)
+
+const marathonUsage = `usage:
+  roll [flags] import -format horaro|lss|csv [-name name] [-channel channel] file...
+  roll [flags] export -id id`
+
+// dbLockTimeout is how long to wait for the database.  The running bot
+// holds it open.
+const dbLockTimeout = 1 * time.Second
+
+// openMarathons opens the marathon module's bucket of the bot's database
+// without starting a bot.
+func openMarathons(config *roll.Config) (*storm.DB, storm.Node, error) {
+	path := config.DBPath
+	if path == "" {
+		path = "bot.db"
+	}
+	db, err := storm.Open(path, storm.BoltOptions(0600, &bolt.Options{Timeout: dbLockTimeout}))
+	if err == bolt.ErrTimeout {
+		return nil, nil, fmt.Errorf("%s is locked.  Is the bot running?", path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, db.From("marathon"), nil
+}
+
+// defaultChannel returns the first configured channel, which the bot uses
+// when none is given.
+func defaultChannel(config *roll.Config) string {
+	if len(config.Channels) > 0 {
+		return config.Channels[0].Name
+	}
+	return strings.ToLower(config.Channel)
+}
+
+// runMarathonCommand runs the import or export subcommand.
+func runMarathonCommand(config *roll.Config, args []string) error {
+	db, marathons, err := openMarathons(config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		format := flags.String("format", "", "File format: horaro, lss or csv")
+		name := flags.String("name", "", "Marathon name")
+		channel := flags.String("channel", defaultChannel(config), "Channel the marathon belongs to")
+		flags.Parse(args[1:])
+
+		var files []io.Reader
+		for _, filename := range flags.Args() {
+			f, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			files = append(files, f)
+		}
+
+		m, err := marathon.Import(*format, *name, files)
+		if err != nil {
+			return err
+		}
+		m.Channel = strings.ToLower(*channel)
+		err = marathons.Save(m)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported marathon %d.\n", m.ID)
+
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		id := flags.Int("id", 0, "Marathon id")
+		flags.Parse(args[1:])
+
+		var m marathon.Marathon
+		err = marathons.One("ID", *id, &m)
+		if err != nil {
+			return err
+		}
+		err = marathon.ExportCSV(os.Stdout, &m)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown command %s\n%s", args[0], marathonUsage)
+	}
+	return nil
+}
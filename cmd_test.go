@@ -1,6 +1,12 @@
 package roll
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/konkers/roll/clocktest"
+)
 
 func TestCmdEngineBadContext(t *testing.T) {
 	engine := NewCmdEngine()
@@ -14,3 +20,171 @@ func TestCmdEngineBadContext(t *testing.T) {
 		t.Errorf("Did not get expected error when invoking command w/ invalid context")
 	}
 }
+
+func TestCmdEngineCooldown(t *testing.T) {
+	engine := NewCmdEngine()
+	runs := 0
+	command := func(cc *CommandContext, args []string) error {
+		runs++
+		return nil
+	}
+
+	engine.AddCommandWithCooldown("global", "test help", command, 0,
+		Cooldown{Global: time.Hour})
+	engine.AddCommandWithCooldown("user", "test help", command, 0,
+		Cooldown{User: time.Hour})
+
+	alice := &CommandContext{User: &twitch.User{Username: "alice"}}
+	bob := &CommandContext{User: &twitch.User{Username: "bob"}}
+	mod := &CommandContext{
+		User:      &twitch.User{Username: "mod"},
+		UserLevel: UserLevelModerator,
+	}
+
+	tests := []struct {
+		cc      *CommandContext
+		command string
+		runs    int
+	}{
+		{alice, "global", 1},
+		{bob, "global", 1},
+		{mod, "global", 2},
+		{mod, "global", 3},
+		{alice, "user", 4},
+		{alice, "user", 4},
+		{bob, "user", 5},
+		{mod, "user", 6},
+	}
+
+	for i, test := range tests {
+		err := engine.Exec(test.cc, test.cc.UserLevel, []string{test.command})
+		if err != nil {
+			t.Errorf("%d: Unexpected error running %s: %v", i, test.command, err)
+		}
+		if runs != test.runs {
+			t.Errorf("%d: %s by %s ran %d commands instead of %d", i,
+				test.command, test.cc.User.Username, runs, test.runs)
+		}
+	}
+
+	remaining := engine.useCooldown("global", alice, time.Now().Add(time.Hour))
+	if remaining != 0 {
+		t.Errorf("Cooldown still has %v remaining after it expired", remaining)
+	}
+
+	engine.RemoveCommand("user")
+	if _, ok := engine.cooldowns["user"]; ok {
+		t.Errorf("Cooldown not removed with command")
+	}
+}
+
+func TestCmdEngineCooldownChannels(t *testing.T) {
+	engine := NewCmdEngine()
+	engine.AddCommandWithCooldown("test", "test help", func(cc *CommandContext, args []string) error {
+		return nil
+	}, 0, Cooldown{Global: time.Hour, User: time.Hour})
+
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	alice := &twitch.User{Username: "alice"}
+	bob := &twitch.User{Username: "bob"}
+	for i, test := range []struct {
+		channel string
+		user    *twitch.User
+		allowed bool
+	}{
+		{"one", alice, true},
+		{"one", bob, false},
+		{"two", alice, true},
+		{"two", bob, false},
+		{"three", bob, true},
+	} {
+		cc := &CommandContext{Channel: test.channel, User: test.user}
+		allowed := engine.useCooldown("test", cc, now) == 0
+		if allowed != test.allowed {
+			t.Errorf("%d: test by %s in %s allowed %v instead of %v", i,
+				test.user.Username, test.channel, allowed, test.allowed)
+		}
+	}
+}
+
+func TestCmdEngineCooldownClock(t *testing.T) {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := NewCmdEngine()
+	runs := 0
+	engine.AddCommandWithCooldown("test", "test help", func(cc *CommandContext, args []string) error {
+		runs++
+		return nil
+	}, 0, Cooldown{Global: time.Minute})
+
+	cc := &CommandContext{Bot: &Bot{clock: clock}, User: &twitch.User{Username: "alice"}}
+	for i, test := range []struct {
+		advance time.Duration
+		runs    int
+	}{
+		{0, 1},
+		{59 * time.Second, 1},
+		{time.Second, 2},
+		{30 * time.Second, 2},
+	} {
+		clock.Advance(test.advance)
+		engine.Exec(cc, cc.UserLevel, []string{"test"})
+		if runs != test.runs {
+			t.Errorf("%d: Command ran %d times instead of %d", i, runs, test.runs)
+		}
+	}
+}
+
+func TestCommandParser(t *testing.T) {
+	tests := []struct {
+		parser CommandParser
+		text   string
+		line   string
+		prefix string
+		ok     bool
+	}{
+		{CommandParser{}, "!game", "game", "!", true},
+		{CommandParser{}, "game", "", "", false},
+		{CommandParser{}, "?game", "", "", false},
+		{CommandParser{Prefixes: []string{"?", "!!"}}, "?game", "game", "?", true},
+		{CommandParser{Prefixes: []string{"?", "!!"}}, "!!game", "game", "!!", true},
+		{CommandParser{Prefixes: []string{"?", "!!"}}, "!game", "", "", false},
+		{CommandParser{Mention: "RollTheRobot"}, "@RollTheRobot game", "game", "!", true},
+		{CommandParser{Mention: "RollTheRobot"}, "@rolltherobot: marathon next", "marathon next", "!", true},
+		{CommandParser{Mention: "RollTheRobot"}, "RollTheRobot, game", "game", "!", true},
+		{CommandParser{Mention: "RollTheRobot"}, "@RollTheRobot", "", "", false},
+		{CommandParser{Mention: "RollTheRobot"}, "hi @RollTheRobot game", "", "", false},
+		{CommandParser{Prefixes: []string{"?"}, Mention: "RollTheRobot"}, "@RollTheRobot game", "game", "?", true},
+		{CommandParser{}, "@RollTheRobot game", "", "", false},
+	}
+
+	for _, test := range tests {
+		line, prefix, ok := test.parser.Parse(test.text)
+		if line != test.line || prefix != test.prefix || ok != test.ok {
+			t.Errorf("%#v.Parse(\"%s\") returned (\"%s\", \"%s\", %v) instead of (\"%s\", \"%s\", %v)",
+				test.parser, test.text, line, prefix, ok, test.line, test.prefix, test.ok)
+		}
+	}
+}
+
+func TestCmdEngineFilter(t *testing.T) {
+	engine := NewCmdEngine()
+	engine.Filter = func(cc *CommandContext, name string) bool {
+		return name != "filtered"
+	}
+
+	for _, text := range []string{"!filtered", "!", "hello"} {
+		ok, err := engine.ExecMessage(&CommandContext{}, &CommandParser{}, text)
+		if ok || err != nil {
+			t.Errorf("ExecMessage(\"%s\") returned (%v, %v)", text, ok, err)
+		}
+	}
+
+	cc := &CommandContext{}
+	ok, _ := engine.ExecMessage(cc, &CommandParser{Prefixes: []string{"?"}}, "?test")
+	if !ok {
+		t.Errorf("ExecMessage() did not run ?test")
+	}
+	if cc.Prefix != "?" {
+		t.Errorf("CommandContext prefix is \"%s\" instead of \"?\"", cc.Prefix)
+	}
+}
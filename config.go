@@ -5,15 +5,35 @@ import (
 	"io/ioutil"
 )
 
+// ChannelConfig is the configuration of a single channel the bot serves.
+type ChannelConfig struct {
+	Name string `json:"name"`
+
+	// Modules enabled in this channel.  All modules are enabled if empty.
+	Modules []string `json:"modules"`
+
+	// Prefixes that start commands.  Defaults to "!".
+	Prefixes []string `json:"prefixes"`
+}
+
 // Config is the bot's configuration
 type Config struct {
-	BotUsername string `json:"bot_username"`
-	Channel     string `json:"channel"`
-	APIOAuth    string `json:"api_oauth"`
-	IRCOAuth    string `json:"irc_oauth"`
-	IRCAddress  string `json:"irc_addr"`
-	APIURLBase  string `json:"api_url_base"`
-	AdminUser   string `json:"admin_user"`
+	BotUsername string          `json:"bot_username"`
+	Channels    []ChannelConfig `json:"channels"`
+
+	// Channel is a shorthand for a single channel with all modules
+	// enabled.  It is ignored if Channels is set.
+	Channel string `json:"channel"`
+
+	APIOAuth   string `json:"api_oauth"`
+	IRCOAuth   string `json:"irc_oauth"`
+	IRCAddress string `json:"irc_addr"`
+	APIURLBase string `json:"api_url_base"`
+	AdminUser  string `json:"admin_user"`
+
+	IRCConnectTimeout Duration `json:"irc_connect_timeout"`
+	IRCReconnectMin   Duration `json:"irc_reconnect_min"`
+	IRCReconnectMax   Duration `json:"irc_reconnect_max"`
 
 	ClientID string `json:"client_id"`
 
@@ -39,3 +59,12 @@ func LoadConfig(fileName string) (*Config, error) {
 	}
 	return &config, nil
 }
+
+// channelConfigs returns the configured channels, falling back to the
+// single Channel if Channels is empty.
+func (c *Config) channelConfigs() []ChannelConfig {
+	if len(c.Channels) == 0 && c.Channel != "" {
+		return []ChannelConfig{{Name: c.Channel}}
+	}
+	return c.Channels
+}
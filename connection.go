@@ -0,0 +1,166 @@
+package roll
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/konkers/roll/clock"
+)
+
+// ConnectionState is the state of the bot's IRC connection.
+type ConnectionState int
+
+const (
+	ConnectionDisconnected = ConnectionState(iota)
+	ConnectionConnecting
+	ConnectionConnected
+)
+
+const (
+	defaultIRCConnectTimeout = 3 * time.Second
+	defaultIRCReconnectMin   = 1 * time.Second
+	defaultIRCReconnectMax   = 2 * time.Minute
+)
+
+// ConnectionStateListener is implemented by modules that want to be told
+// when the IRC connection goes up or down.
+type ConnectionStateListener interface {
+	ConnectionStateChanged(state ConnectionState)
+}
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionDisconnected:
+		return "disconnected"
+	case ConnectionConnecting:
+		return "connecting"
+	case ConnectionConnected:
+		return "connected"
+	default:
+		return "???"
+	}
+}
+
+// ConnectionState returns the current state of the IRC connection.
+func (b *Bot) ConnectionState() ConnectionState {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	return b.connState
+}
+
+func (b *Bot) setConnectionState(state ConnectionState) {
+	b.stateMu.Lock()
+	changed := b.connState != state
+	b.connState = state
+	b.stateMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	log.Printf("IRC %v.", state)
+	b.outQ.wake()
+	for _, name := range b.moduleNames {
+		if listener, ok := b.modules[name].(ConnectionStateListener); ok {
+			listener.ConnectionStateChanged(state)
+		}
+	}
+}
+
+func (b *Bot) handleConnect() {
+	select {
+	case b.connectC <- struct{}{}:
+	default:
+	}
+}
+
+// dialIRC makes a single attempt to connect to IRC.  On success it returns
+// a channel that receives the error the connection eventually ends with.
+func (b *Bot) dialIRC() (<-chan error, error) {
+	// Throw away any stale connect notification from a previous attempt.
+	select {
+	case <-b.connectC:
+	default:
+	}
+
+	b.setConnectionState(ConnectionConnecting)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- b.ircClient.Connect()
+	}()
+
+	select {
+	case err := <-errC:
+		b.setConnectionState(ConnectionDisconnected)
+		if err == nil {
+			err = fmt.Errorf("connection closed")
+		}
+		return nil, fmt.Errorf("Can't connect to irc: %v", err)
+	case <-clock.After(b.clock, b.Config.IRCConnectTimeout.Duration):
+		b.ircClient.Disconnect()
+		b.setConnectionState(ConnectionDisconnected)
+		return nil, fmt.Errorf("IRC connect timed out")
+	case <-b.connectC:
+		b.setConnectionState(ConnectionConnected)
+		return errC, nil
+	}
+}
+
+// superviseIRC waits for the IRC connection to drop and reconnects until
+// the bot is closed.
+func (b *Bot) superviseIRC(errC <-chan error) {
+	defer close(b.ircDone)
+
+	for {
+		select {
+		case err := <-errC:
+			select {
+			case <-b.closeC:
+				return
+			default:
+			}
+			log.Printf("IRC connection lost: %v", err)
+		case <-b.closeC:
+			return
+		}
+		b.setConnectionState(ConnectionDisconnected)
+
+		errC = b.reconnectIRC()
+		if errC == nil {
+			// Closed while reconnecting.
+			return
+		}
+		b.joinChannels()
+	}
+}
+
+// reconnectIRC retries connecting to IRC with exponential backoff.  It
+// returns nil if the bot is closed before a connection is made.
+func (b *Bot) reconnectIRC() <-chan error {
+	backoff := b.Config.IRCReconnectMin.Duration
+	for {
+		log.Printf("Reconnecting to IRC in %v.", backoff)
+		select {
+		case <-clock.After(b.clock, backoff):
+		case <-b.closeC:
+			return nil
+		}
+
+		errC, err := b.dialIRC()
+		if err == nil {
+			return errC
+		}
+		log.Printf("IRC reconnect failed: %v", err)
+		backoff = nextBackoff(backoff, b.Config.IRCReconnectMax.Duration)
+	}
+}
+
+// nextBackoff doubles d without exceeding max.
+func nextBackoff(d time.Duration, max time.Duration) time.Duration {
+	d *= 2
+	if d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,88 @@
+package roll
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm"
+)
+
+type stateTestModule struct {
+	mu     sync.Mutex
+	states []ConnectionState
+}
+
+func (m *stateTestModule) Start() error {
+	return nil
+}
+
+func (m *stateTestModule) Stop() error {
+	return nil
+}
+
+func (m *stateTestModule) ConnectionStateChanged(state ConnectionState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states = append(m.states, state)
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		{time.Second, time.Minute, 2 * time.Second},
+		{20 * time.Second, time.Minute, 40 * time.Second},
+		{40 * time.Second, time.Minute, time.Minute},
+		{time.Minute, time.Minute, time.Minute},
+	}
+
+	for _, test := range tests {
+		d := nextBackoff(test.d, test.max)
+		if d != test.expected {
+			t.Errorf("nextBackoff(%v, %v) returned %v instead of %v",
+				test.d, test.max, d, test.expected)
+		}
+	}
+}
+
+func TestConnectionStateListener(t *testing.T) {
+	module := &stateTestModule{}
+	err := RegisterModuleFactory(func(bot *Bot, db storm.Node) (Module, error) {
+		return module, nil
+	}, "state_test")
+	if err != nil {
+		t.Fatalf("Unexpected error from RegisterModuleFactory(): %v", err)
+	}
+
+	b, mock := newTestBot(t)
+	err = b.AddModule("state_test")
+	if err != nil {
+		t.Fatalf("Unexpected error from AddModule(): %v", err)
+	}
+
+	if b.ConnectionState() != ConnectionDisconnected {
+		t.Errorf("New bot is %v instead of disconnected", b.ConnectionState())
+	}
+
+	connectTestBot(t, b, mock)
+	if b.ConnectionState() != ConnectionConnected {
+		t.Errorf("Connected bot is %v instead of connected", b.ConnectionState())
+	}
+
+	module.mu.Lock()
+	states := module.states
+	module.mu.Unlock()
+	if len(states) != 2 ||
+		states[0] != ConnectionConnecting ||
+		states[1] != ConnectionConnected {
+		t.Errorf("Unexpected connection states %v", states)
+	}
+
+	b.Close()
+	if b.ConnectionState() != ConnectionDisconnected {
+		t.Errorf("Closed bot is %v instead of disconnected", b.ConnectionState())
+	}
+}
@@ -0,0 +1,56 @@
+package roll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("No tz data: %v", err)
+	}
+	// A Monday.
+	base := time.Date(2019, 3, 4, 12, 30, 0, 0, berlin)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2019, 3, 4, 12, 31, 0, 0, berlin)},
+		{"*/15 * * * *", time.Date(2019, 3, 4, 12, 45, 0, 0, berlin)},
+		{"0 19 * * tue", time.Date(2019, 3, 5, 19, 0, 0, 0, berlin)},
+		{"0 19 * * 1", time.Date(2019, 3, 4, 19, 0, 0, 0, berlin)},
+		{"0 9-17/4 * * *", time.Date(2019, 3, 4, 13, 0, 0, 0, berlin)},
+		{"0 0 1 * *", time.Date(2019, 4, 1, 0, 0, 0, 0, berlin)},
+		{"0 0 29 feb *", time.Date(2020, 2, 29, 0, 0, 0, 0, berlin)},
+		{"30 12 * * 7", time.Date(2019, 3, 10, 12, 30, 0, 0, berlin)},
+		// Either day field matches when both are restricted.
+		{"0 0 15 * fri", time.Date(2019, 3, 8, 0, 0, 0, 0, berlin)},
+		{"@daily", time.Date(2019, 3, 5, 0, 0, 0, 0, berlin)},
+		// 02:30 doesn't exist on the day clocks go forward.
+		{"30 2 31 3 *", time.Date(2020, 3, 31, 2, 30, 0, 0, berlin)},
+	}
+	for _, test := range tests {
+		s, err := ParseCron(test.expr, "Europe/Berlin")
+		if err != nil {
+			t.Errorf("ParseCron(%s) returned error: %v", test.expr, err)
+			continue
+		}
+		next := s.Next(base)
+		if !next.Equal(test.next) {
+			t.Errorf("%s: Next() returned %v instead of %v", test.expr, next, test.next)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *"} {
+		_, err := ParseCron(expr, "")
+		if err == nil {
+			t.Errorf("ParseCron(%s) didn't return an error", expr)
+		}
+	}
+	_, err = ParseCron("* * * * *", "Not/AZone")
+	if err == nil {
+		t.Errorf("ParseCron() accepted a bad time zone")
+	}
+}
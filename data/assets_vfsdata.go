@@ -29,14 +29,56 @@ var Assets = func() http.FileSystem {
 		},
 		"/templates": &vfsgen۰DirInfo{
 			name:    "templates",
-			modTime: time.Date(2018, 9, 28, 22, 36, 32, 728467178, time.UTC),
+			modTime: time.Date(2026, 10, 18, 10, 5, 9, 277893403, time.UTC),
+		},
+		"/templates/commands.html": &vfsgen۰CompressedFileInfo{
+			name:             "commands.html",
+			modTime:          time.Date(2026, 10, 18, 8, 59, 9, 955749509, time.UTC),
+			uncompressedSize: 388,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\x3f\x4f\x04\x21\x10\xc5\x7b\x3f\xc5\x14\xb6\xc7\xe4\xfa\x09\x8d\x16\x16\xc6\xd8\x18\x6b\xee\x18\x85\x84\x3f\x17\x76\x34\x1a\xc2\x77\x37\x39\xe4\x96\x4d\xb6\x7b\xef\xc1\x6f\x1e\x0c\x39\x89\x41\xdf\x01\x00\x90\x63\x63\xbb\xbc\x5a\xf1\x12\x58\x97\x1c\x02\x1c\xe0\x9c\x63\x34\xc9\x2e\x84\x3d\xee\x04\xae\x08\x9d\xb2\xfd\x9d\x68\x77\xd4\x0f\x37\xc6\x1d\xe7\xb9\xe6\x14\x78\xf5\x3d\x2b\x9a\xc4\x0d\x82\x50\xdc\xd5\x3f\xf2\x72\x2e\xfe\x22\x3e\xa7\x5b\xf6\xee\x72\xd7\x28\x65\x3b\xa5\xd6\x03\x14\x93\x3e\x19\xd4\xa8\x6e\x6d\xa7\xc7\xea\x5a\xef\xd5\x6b\xe1\x0f\xff\xd3\x5a\xad\xea\xc5\x44\x6e\x8d\x50\xec\xff\xa9\x7a\xe2\x70\xd9\x24\x5f\x0b\x97\x67\xfe\xe6\x00\xea\x6d\xc8\x71\x61\xf7\x21\x9c\xec\x54\x4e\x38\xfd\x9a\xb0\xef\x8a\xb0\x2f\xff\x6f\x00\x14\xaa\xe4\x48\x84\x01\x00\x00"),
 		},
 		"/templates/index.html": &vfsgen۰CompressedFileInfo{
 			name:             "index.html",
 			modTime:          time.Date(2018, 9, 28, 22, 37, 36, 385552769, time.UTC),
 			uncompressedSize: 113,
 
-			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb2\xc9\x28\xc9\xcd\xb1\xe3\x52\x50\x50\x50\xb0\xc9\x48\x4d\x4c\x81\x30\xc1\xdc\x92\xcc\x92\x9c\x54\xbb\xa2\xfc\x9c\x1c\x1b\x7d\x08\x1b\xa2\x4c\x1f\xa1\xce\x26\x29\x3f\xa5\x12\xa1\x25\x3c\x35\x27\x39\x3f\x37\x55\xa1\x24\x5f\x01\xa4\x4d\x0f\xaa\x1e\xa2\xc8\x46\x1f\x6c\x15\x20\x00\x00\xff\xff\x34\x7d\xe2\xfe\x71\x00\x00\x00"),
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb2\xc9\x28\xc9\xcd\xb1\xe3\x52\x50\x50\x50\xb0\xc9\x48\x4d\x4c\x81\x30\xc1\xdc\x92\xcc\x92\x9c\x54\xbb\xa2\xfc\x9c\x1c\x1b\x7d\x08\x1b\xa2\x4c\x1f\xa1\xce\x26\x29\x3f\xa5\x12\xa1\x25\x3c\x35\x27\x39\x3f\x37\x55\xa1\x24\x5f\x01\xa4\x4d\x0f\xaa\x1e\xa2\xc8\x46\x1f\x6c\x15\x60\x00\x34\x7d\xe2\xfe\x71\x00\x00\x00"),
+		},
+		"/templates/marathon_archive.html": &vfsgen۰CompressedFileInfo{
+			name:             "marathon_archive.html",
+			modTime:          time.Date(2026, 10, 18, 9, 13, 54, 668612561, time.UTC),
+			uncompressedSize: 610,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x52\xc1\x6a\xc3\x30\x0c\xbd\xf7\x2b\x44\xe8\x35\x76\xda\xc3\x0e\xc3\xf5\x2e\x65\xa3\x87\x8d\xc1\x6e\xbb\xa9\x58\x9d\x03\x4e\x3c\x1c\xc1\x18\xc2\xff\x3e\x6a\xd3\x25\x65\xdd\x25\xe4\x3d\xe5\xbd\x27\x29\x32\x9e\x87\x60\x57\x00\x00\xc6\x13\xba\xfa\x5a\x20\xf7\x1c\xc8\xa6\x18\x02\xb4\xf0\x89\x13\xc3\x80\x09\xd9\xc7\x71\x32\xba\x16\xab\x4e\xcf\x42\x73\x8c\xee\x7b\xe1\xe1\x37\xf6\xf5\xac\x7c\x9e\x95\x7e\xb3\xcc\xc0\x63\xa0\x19\x57\x2e\x59\xc3\xde\x5e\x24\x46\xb3\x2f\xc4\x1b\x63\x62\x72\xbf\xf8\x09\x07\x9a\x2a\xd2\x9c\xae\x4d\x44\x5a\x48\x38\x7e\x10\xac\x11\xee\x77\xa0\x72\xfe\x13\x72\x45\x54\xd2\x59\x83\xe0\x13\x9d\x76\x8d\xd2\x0f\xbd\xdb\x89\xa8\x4b\x1f\xea\xb0\xcf\xb9\xb1\x22\x5f\x3d\x7b\x98\xe9\x17\x1c\x28\x67\x11\x75\x7e\x50\x98\x28\xe7\x4b\x0d\x44\xd6\x78\x6d\x20\x42\xa3\xcb\xd9\x68\x3c\x77\xed\x6e\x37\x21\xd2\x9f\x60\x8c\x0c\xaa\xcc\xac\x0e\xd3\x3b\xa5\x58\x42\x2a\xf1\x18\xd3\x80\x0c\xcd\xb6\xeb\xee\xda\x6e\xd3\x76\xdb\x66\x61\xfd\xbf\xad\xda\xc7\x91\x72\x06\x0d\x22\x81\xc6\xc5\x14\x65\x99\xb7\xc4\xb7\x77\x5b\x92\x56\x8b\x6f\xe6\xff\x68\x74\xbd\x01\xa3\xeb\x69\xfd\x0c\x00\xe1\x38\xbe\x83\x62\x02\x00\x00"),
+		},
+		"/templates/marathon_overlay.html": &vfsgen۰CompressedFileInfo{
+			name:             "marathon_overlay.html",
+			modTime:          time.Date(2026, 10, 18, 10, 5, 9, 277893403, time.UTC),
+			uncompressedSize: 1567,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x55\x61\x6f\xdb\x36\x10\xfd\xbe\x5f\xf1\x26\x74\x85\x84\xd4\x92\xd2\x02\xf9\x60\xcb\x1e\xb0\xae\x1b\x8a\xa1\xc1\x80\xee\x0f\xd0\xe2\x29\x12\x2a\x91\x1a\x79\xb2\xe3\x11\xfc\xef\x83\x44\xc7\xb1\x12\x15\x25\x60\xe3\x48\xde\x7b\x77\xf7\xee\x4c\x17\x35\x77\xed\xee\x27\x00\x28\x6a\x12\x32\x98\xd3\x96\x1b\x6e\x69\x67\x74\xdb\x62\x85\x4e\x18\xc1\xb5\x56\xd0\x07\x32\xad\x38\x15\x59\xb8\x7e\x76\xef\x88\x05\x6a\xe6\x7e\x45\xff\x0e\xcd\x61\x1b\x19\xaa\x0c\xd9\x3a\x42\xa9\x15\x93\xe2\x6d\x74\x9b\x47\x57\x00\xcb\xa7\x6b\x82\x71\xed\xb5\x3c\xc1\x61\x2f\xca\x6f\x0f\x46\x0f\x4a\xae\xc1\x46\x28\xdb\x0b\x43\x8a\x37\x28\x75\xab\xcd\x1a\xc7\xba\x61\xda\xa0\xd2\x8a\x57\x95\xe8\x9a\xf6\xb4\x86\x15\xca\xae\x2c\x99\xa6\xda\x80\xe9\x91\x57\xb6\x16\x52\x1f\xd7\x78\xdf\x3f\x5e\x3e\xfb\x56\x94\xdf\x36\xf0\xb3\xa0\xe9\x83\xe8\x08\x2e\xd0\xd9\xe6\x3f\x5a\xe3\x3d\x75\xaf\xbc\xb8\xe9\xc8\xcc\xdd\x3e\x8c\x6e\xb3\x2c\x3a\xad\xb4\xed\x45\x49\xd7\xf0\x22\xbb\x2a\xb5\xc8\x9e\x65\x2e\xc6\x7a\x9f\x15\x70\x6e\x85\x63\xc3\x35\xd2\x8f\x83\x19\x0b\xf6\x57\x1c\xb2\x39\xa0\x6c\x85\xb5\xdb\x68\xcc\x37\xda\x39\x97\xde\x8b\x8e\xbc\x77\x2e\x80\xbe\x9e\x2c\x53\xe7\x3d\x62\xe7\x52\xef\x13\xe7\x48\x49\xef\x8b\x4c\x36\x87\xdd\x22\xd1\x54\x52\x84\x46\x5e\x4c\x29\x58\xac\xa8\x15\xbd\x25\xb9\x8d\x9c\x7b\x93\x7e\x0a\x9b\xf4\x2b\x95\x5a\x49\xeb\x7d\xe4\x5c\x53\x21\xfd\x5b\x0c\x96\xa4\xf7\x01\xd2\x4f\xbb\x6d\xc4\x66\xa0\xe8\x1c\x78\xe7\xdc\xc8\x8a\x74\x31\x87\xdd\x9f\x93\xea\xee\x4d\x7a\x3f\x74\x7b\x32\xde\x43\x57\xd3\xfe\x1f\xcd\xa2\x7d\x85\x19\xc5\xa1\xd6\xd2\x0f\x34\x09\x52\x7c\x39\x4f\xeb\x45\xa1\x74\xfc\x0a\xf0\xa7\xbb\x65\x79\xa6\x30\xe3\xf9\x42\x57\xee\xe9\xf1\x65\x4b\x76\xe3\x19\x86\x7e\x8d\x4b\x3b\x7e\x48\x58\xd8\xd2\x34\x3d\xcf\x27\x3f\xcb\xf0\x17\x51\x0f\xae\x09\x61\xd2\xcc\xa0\x54\xa3\x1e\xb0\x27\x3e\x12\x29\x9c\x7f\x4e\x64\xd3\x19\xf0\x20\xcc\x19\xb0\x85\xd4\xe5\xd0\x91\xe2\xf4\x81\xf8\x53\x4b\xa3\xf9\xdb\xe9\xb3\x8c\xcf\xdd\x4d\x36\x33\x64\x53\x21\x0e\xc8\xb7\x6f\xf1\xf3\x64\xa5\x63\x33\x2d\x71\x1a\xfa\x99\xc0\xcd\x10\x4f\xf1\x2c\x0b\xc3\x24\xb1\xc5\xef\x82\x29\x55\xfa\x18\x27\xc8\x70\x9b\xe7\x39\x56\xe8\x85\xb1\xf4\x47\xab\x05\xc7\x73\xd2\xf3\x5c\x25\x9b\x57\xa4\x96\xf8\xb3\x62\x32\x07\xd1\xc6\xd5\xa0\x4a\x6e\xb4\x8a\x97\xa2\x3f\x65\x30\xc6\xfe\x22\xb8\x4e\x3b\xf1\x18\xe7\xef\x82\x5d\xb5\x5a\x9b\x78\x29\xa5\x73\xc2\x49\xb2\xf9\x2e\x63\x2f\x46\xce\x4b\x70\x95\xc0\xc1\x10\x0f\x46\x41\xa1\xc0\x6d\x8e\x5f\x11\xe5\x11\x6e\xa0\xb0\x46\x34\x19\x1b\xf8\x65\xc2\x50\xf7\xf8\x0c\x7d\x0c\x6f\x1f\xb6\xd7\x29\x4a\x64\xf8\x70\x97\xe7\x09\x6e\x10\xad\x23\xdc\x2c\x92\x8c\xab\x17\x32\x7e\x01\xbc\xcb\x13\xfc\x82\xbb\x67\xf0\xe4\x24\xc3\xd9\xeb\x74\xfc\xbb\x49\x84\x17\x37\xb3\xc7\xe9\x6a\x1c\x8b\x2c\x3c\x49\x45\x16\xfe\x17\xfe\x1f\x00\x44\x50\x65\xd4\x1f\x06\x00\x00"),
+		},
+		"/templates/marathon_schedule.html": &vfsgen۰CompressedFileInfo{
+			name:             "marathon_schedule.html",
+			modTime:          time.Date(2026, 10, 18, 9, 10, 55, 890728410, time.UTC),
+			uncompressedSize: 1086,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x54\x4d\x6f\xd4\x30\x10\xbd\xf3\x2b\x46\xcb\x05\x0e\xb5\xd9\x0a\x2e\x95\xf1\x85\x42\x85\xc4\x56\x48\xed\x89\x9b\xb7\x9e\xd6\x06\x7f\x20\x67\xa2\x6a\x65\xf9\xbf\xa3\xd8\x9b\x4d\xb2\xc0\x6a\x7b\x49\x3c\xf6\xcc\x7b\x9e\xf7\x26\x11\x86\xbc\x93\xaf\x00\x00\x84\x41\xa5\xdb\xb2\x86\x64\xc9\xa1\x4c\xd1\x39\xb8\x80\x9c\x9f\x2d\x19\x60\x1b\x95\x14\x99\x18\xd8\xad\xf2\x58\x4a\xce\x6c\x78\xa0\xeb\xb0\x14\xbf\x3f\xcb\x19\x83\x2e\x05\xba\x07\x83\xba\x77\x28\x78\x83\x6a\x2c\x7c\xa2\x11\xdb\xa8\x77\x33\x46\xb3\x96\xe7\xf0\x6c\x96\x3c\x82\x9b\xf5\x04\x92\xf3\x05\x34\x88\x4f\x7d\x4a\x18\xa8\x94\x19\xc1\xa5\xbc\x8d\xcf\xf0\xdb\xa9\x9d\x0d\x4f\x57\x90\xf3\x1e\x1f\xde\xe4\x4c\xd6\x23\xb0\x52\xde\x0a\x6e\x2e\x97\x80\x95\x67\xa6\x8c\xda\x8e\xed\x4c\x7b\x49\x0a\x32\xf2\xb5\xe0\x64\xea\xea\x46\x79\x3c\x04\x77\xbb\x8e\xd0\x1f\xc2\xcf\x1d\x59\xaf\x68\x76\x4e\x8a\xfa\xee\x10\xde\x5b\xbf\x38\x4b\x34\x45\x93\xa8\x46\x0a\x4e\x69\x79\x8f\xe1\xb6\x49\x85\x27\x04\x36\x66\x96\xb2\xc8\x10\xc7\x25\x6d\x53\xcb\x41\x8c\xde\x6f\x31\x0d\x92\x92\xfe\x5f\x52\x13\x77\xe8\x8e\x7d\xb3\xe1\x57\x29\x42\x81\x49\xf8\xf8\x71\x55\x4d\x5a\xc9\xbd\x2d\x39\xb7\xa4\xd1\xc0\xbf\xeb\xb8\x92\x07\x0b\xcf\xe2\x6b\x2a\x4e\xd3\xf0\x82\xd2\x51\xf1\x5a\x7c\xdd\x27\x45\x36\x86\xb3\x40\xba\x6a\xcd\x78\x83\x1a\x9c\x2e\x68\x73\x74\x53\xdb\x3e\x95\xc7\xbe\xa7\xf8\x13\x1f\x08\x35\xfb\x12\x93\x57\x04\xab\x4d\x0c\xb0\xfe\x70\xf5\xee\x3d\x6c\xee\xee\x57\xa7\xcb\xed\x23\x84\x48\x93\xcd\x9a\x7d\xed\x7e\x60\x8a\x43\x53\x1a\x1d\x29\x60\xd7\xc3\xeb\x44\x93\xff\x1e\x9f\xa3\x61\xe7\xb3\x69\x17\xbc\x7d\xb1\x82\xb7\xdf\xc6\x9f\x01\x00\x44\xc4\x6b\xb1\x3e\x04\x00\x00"),
+		},
+		"/templates/poll.html": &vfsgen۰CompressedFileInfo{
+			name:             "poll.html",
+			modTime:          time.Date(2026, 10, 18, 9, 5, 53, 749616435, time.UTC),
+			uncompressedSize: 566,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x92\x31\x6f\xf3\x20\x10\x86\xf7\xef\x57\x9c\x90\xbe\xd1\x10\x4b\xf5\x12\x11\x6f\xed\xd8\xa4\x1d\xba\x3b\xe6\x52\x2c\x61\xa0\x70\xae\x14\x21\xfe\x7b\xe5\xb8\x96\xed\xa6\xea\x64\xf3\xea\xe5\xe1\x41\x87\xd4\xd4\x9b\xfa\x1f\x00\x80\xd4\xd8\xa8\xe9\xf7\xb6\xa4\x8e\x0c\xd6\xc1\x19\x03\x05\x78\x67\x8c\x14\x53\xb4\x54\x7a\xa4\x06\x34\x91\x2f\xf0\x63\xe8\x3e\x0f\x2c\xe0\x25\x60\xd4\x0c\x5a\x67\x09\x2d\x1d\x58\xc5\xbe\xe9\x62\xc1\xcb\xb3\x53\xd7\x05\x93\x52\x01\xdd\x05\x78\xce\x0b\x59\x97\x75\x4a\xfc\x65\xc0\x48\x9d\xb3\x39\x4b\xa1\xcb\xb5\x5b\x73\x5e\x8b\xcc\x94\xd0\xd8\x77\x04\xfe\x8a\x71\x30\x14\x73\xde\x14\x24\x85\x5a\x92\x1a\xb9\x47\x3f\x53\x49\xcd\xd9\x9b\x23\x8c\xdb\xe8\x84\xa1\x45\x4b\x39\xff\x9f\x52\x41\xe1\xfe\x4c\xb4\x6a\x2d\x2e\x7e\xa8\xcd\x97\x3b\x7a\xb4\xeb\x9e\xaf\x1f\xad\x8a\x90\x12\x1f\xbf\xfc\xc9\x85\xbe\x21\x60\x65\xb5\xdf\x3d\xec\x77\x15\x1b\x4d\xfc\x16\x83\x26\xe2\x16\x71\x1a\x87\xd3\x1a\x17\x51\xdd\xb7\x37\x5e\xbf\xef\x7f\x76\xb7\xc1\x46\xb8\x22\xf1\x3f\x08\x52\x4c\x13\x93\x62\x7a\x2e\x5f\x03\x00\xab\xdb\x39\xb0\x36\x02\x00\x00"),
+		},
+		"/templates/quotes.html": &vfsgen۰CompressedFileInfo{
+			name:             "quotes.html",
+			modTime:          time.Date(2026, 10, 18, 9, 0, 16, 27123358, time.UTC),
+			uncompressedSize: 428,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x51\xcb\x4a\x04\x31\x10\xbc\xfb\x15\xcd\x7a\x9e\xe9\xcc\x1e\x3c\x35\xb9\x28\x7a\x13\x04\x7f\x20\x43\x1a\xb3\x90\x07\xc6\x5e\x50\x42\xfe\x5d\x9c\xec\xec\x64\x64\x6f\x5d\x95\xaa\xea\xa2\x43\x4e\x82\xd7\x77\x00\x00\xe4\xd8\xd8\x36\x2e\x50\x4e\xe2\x59\xe7\xe4\x3d\x0c\xf0\x79\x4e\xc2\x5f\x84\x8d\x6c\x7a\xdc\x0c\x34\x27\xfb\xd3\x79\xdd\xa4\xdf\x2e\x0e\x37\xf5\x99\x66\xf6\xbc\xe1\xc6\x65\x4d\xe2\xf4\xa3\x33\x31\xb2\x27\x14\xb7\xe0\xfb\xeb\xb4\x24\x5d\xd1\x8b\x09\x1b\x78\x32\xeb\x0b\x4a\xde\xe7\x96\x32\x40\x36\xf1\x83\x61\xac\xf5\xc6\x46\xab\x4b\x19\x2f\x4b\x6b\x25\x14\xbb\x92\xaf\xe7\x30\x73\xde\x73\xef\xfc\x2d\x7b\xe6\xaf\xc7\x3f\xcd\x29\xf0\xf8\x9c\x72\x30\x02\x87\xa3\x52\x0f\x83\x9a\x06\x75\x3c\xac\xaa\x9b\x15\x39\xda\xae\x1e\x61\x77\x21\xc2\x76\x55\xc2\xf6\x49\xbf\x03\x00\x4e\x4d\x37\x3d\xac\x01\x00\x00"),
 		},
 		"/wiki": &vfsgen۰DirInfo{
 			name:    "wiki",
@@ -54,7 +96,13 @@ var Assets = func() http.FileSystem {
 		fs["/wiki"].(os.FileInfo),
 	}
 	fs["/templates"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
+		fs["/templates/commands.html"].(os.FileInfo),
 		fs["/templates/index.html"].(os.FileInfo),
+		fs["/templates/marathon_archive.html"].(os.FileInfo),
+		fs["/templates/marathon_overlay.html"].(os.FileInfo),
+		fs["/templates/marathon_schedule.html"].(os.FileInfo),
+		fs["/templates/poll.html"].(os.FileInfo),
+		fs["/templates/quotes.html"].(os.FileInfo),
 	}
 	fs["/wiki"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
 		fs["/wiki/test.md"].(os.FileInfo),
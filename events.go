@@ -0,0 +1,161 @@
+package roll
+
+import (
+	"log"
+	"strconv"
+
+	twitch "github.com/gempir/go-twitch-irc"
+)
+
+// EventType is the kind of a chat Event.
+type EventType int
+
+const (
+	// A chat message, including ones that run commands.
+	EventMessage = EventType(iota)
+	// Subs, resubs, gifted subs, raids, and other USERNOTICEs.
+	EventUserNotice
+	// A user was timed out or banned, or chat was cleared.
+	EventClearChat
+	EventJoin
+	EventPart
+	// Channel settings such as slow or followers only mode changed.
+	EventRoomState
+)
+
+// Event is a chat event.  User and Message are nil for joins and parts.
+type Event struct {
+	Type     EventType
+	Channel  string
+	Username string
+	User     *twitch.User
+	Message  *twitch.Message
+
+	// Set on EventMessage if the message ran a command.
+	Command bool
+}
+
+// EventSubscriber is implemented by anything that wants chat events.
+// Modules implementing it are subscribed automatically and only get events
+// from channels they are enabled in.  Events are delivered synchronously
+// from the IRC client so HandleEvent should not block.
+type EventSubscriber interface {
+	HandleEvent(event *Event)
+}
+
+// EventHandlerFunc adapts a function to an EventSubscriber.
+type EventHandlerFunc func(event *Event)
+
+func (f EventHandlerFunc) HandleEvent(event *Event) {
+	f(event)
+}
+
+func (t EventType) String() string {
+	switch t {
+	case EventMessage:
+		return "message"
+	case EventUserNotice:
+		return "usernotice"
+	case EventClearChat:
+		return "clearchat"
+	case EventJoin:
+		return "join"
+	case EventPart:
+		return "part"
+	case EventRoomState:
+		return "roomstate"
+	default:
+		return "???"
+	}
+}
+
+// MsgID returns the kind of a user notice (ex: sub, resub, raid).
+func (e *Event) MsgID() string {
+	if e.Message == nil {
+		return ""
+	}
+	return e.Message.Tags["msg-id"]
+}
+
+// Bits returns the number of bits cheered with a message.
+func (e *Event) Bits() int {
+	if e.Message == nil {
+		return 0
+	}
+	bits, err := strconv.Atoi(e.Message.Tags["bits"])
+	if err != nil {
+		return 0
+	}
+	return bits
+}
+
+// Subscribe registers sub for events from all channels.
+func (b *Bot) Subscribe(sub EventSubscriber) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	b.subscribers = append(b.subscribers, sub)
+}
+
+func (b *Bot) publish(event *Event) {
+	for _, name := range b.moduleNames {
+		sub, ok := b.modules[name].(EventSubscriber)
+		if ok && b.moduleEnabled(event.Channel, name) {
+			sub.HandleEvent(event)
+		}
+	}
+
+	b.subMu.Lock()
+	subs := b.subscribers
+	b.subMu.Unlock()
+	for _, sub := range subs {
+		sub.HandleEvent(event)
+	}
+}
+
+func (b *Bot) publishMessage(t EventType, channel string, user twitch.User, message twitch.Message) {
+	if b.channelConfig(channel) == nil {
+		log.Printf("Ignoring %v from unknown channel %s.", t, channel)
+		return
+	}
+	b.publish(&Event{
+		Type:     t,
+		Channel:  channel,
+		Username: user.Username,
+		User:     &user,
+		Message:  &message,
+	})
+}
+
+func (b *Bot) handleUsernotice(channel string, user twitch.User, message twitch.Message) {
+	b.publishMessage(EventUserNotice, channel, user, message)
+}
+
+func (b *Bot) handleClearchat(channel string, user twitch.User, message twitch.Message) {
+	b.publishMessage(EventClearChat, channel, user, message)
+}
+
+func (b *Bot) handleRoomstate(channel string, user twitch.User, message twitch.Message) {
+	b.publishMessage(EventRoomState, channel, user, message)
+}
+
+func (b *Bot) handleJoin(channel string, username string) {
+	if b.channelConfig(channel) == nil {
+		return
+	}
+	b.publish(&Event{
+		Type:     EventJoin,
+		Channel:  channel,
+		Username: username,
+	})
+}
+
+func (b *Bot) handlePart(channel string, username string) {
+	if b.channelConfig(channel) == nil {
+		return
+	}
+	b.publish(&Event{
+		Type:     EventPart,
+		Channel:  channel,
+		Username: username,
+	})
+}
@@ -0,0 +1,96 @@
+package roll
+
+import (
+	"testing"
+
+	"github.com/asdine/storm"
+	twitch "github.com/gempir/go-twitch-irc"
+)
+
+type eventTestModule struct {
+	events []*Event
+}
+
+func (m *eventTestModule) Start() error {
+	return nil
+}
+
+func (m *eventTestModule) Stop() error {
+	return nil
+}
+
+func (m *eventTestModule) HandleEvent(event *Event) {
+	m.events = append(m.events, event)
+}
+
+func TestEventSubscribers(t *testing.T) {
+	module := &eventTestModule{}
+	err := RegisterModuleFactory(func(bot *Bot, db storm.Node) (Module, error) {
+		return module, nil
+	}, "event_test")
+	if err != nil {
+		t.Fatalf("Unexpected error from RegisterModuleFactory(): %v", err)
+	}
+
+	b, _ := newTestBot(t)
+	b.Config.Channels = []ChannelConfig{
+		{Name: "testchan"},
+		{Name: "otherchan", Modules: []string{"other"}},
+	}
+	err = b.AddModule("event_test")
+	if err != nil {
+		t.Fatalf("Unexpected error from AddModule(): %v", err)
+	}
+
+	var all []*Event
+	b.Subscribe(EventHandlerFunc(func(event *Event) {
+		all = append(all, event)
+	}))
+
+	b.handleMessage("testchan", twitch.User{Username: "viewer"},
+		twitch.Message{Text: "hello"})
+	b.handleJoin("otherchan", "viewer")
+	b.handlePart("unknownchan", "viewer")
+	b.handleUsernotice("testchan", twitch.User{Username: "viewer"},
+		twitch.Message{Tags: map[string]string{"msg-id": "resub"}})
+
+	if len(module.events) != 2 {
+		t.Fatalf("Module got %d events instead of 2", len(module.events))
+	}
+	if len(all) != 3 {
+		t.Fatalf("Subscriber got %d events instead of 3", len(all))
+	}
+
+	msg := module.events[0]
+	if msg.Type != EventMessage || msg.Command || msg.Username != "viewer" ||
+		msg.Message.Text != "hello" {
+		t.Errorf("Unexpected message event %#v", msg)
+	}
+
+	if all[1].Type != EventJoin || all[1].Channel != "otherchan" || all[1].User != nil {
+		t.Errorf("Unexpected join event %#v", all[1])
+	}
+
+	notice := module.events[1]
+	if notice.Type != EventUserNotice || notice.MsgID() != "resub" {
+		t.Errorf("Unexpected usernotice event %#v", notice)
+	}
+}
+
+func TestEventBits(t *testing.T) {
+	tests := []struct {
+		event Event
+		bits  int
+	}{
+		{Event{}, 0},
+		{Event{Message: &twitch.Message{}}, 0},
+		{Event{Message: &twitch.Message{Tags: map[string]string{"bits": "100"}}}, 100},
+		{Event{Message: &twitch.Message{Tags: map[string]string{"bits": "x"}}}, 0},
+	}
+
+	for _, test := range tests {
+		if test.event.Bits() != test.bits {
+			t.Errorf("Bits() returned %d instead of %d", test.event.Bits(), test.bits)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package roll
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	followerPageSize        = 100
+	followerRefreshInterval = 1 * time.Minute
+)
+
+// Follower is a user following a channel.
+type Follower struct {
+	UserID string
+	Since  time.Time
+}
+
+// LatestFollowersFetcher fetches the IDs of channel's newest followers and
+// its follower count.
+type LatestFollowersFetcher func(channel string) ([]string, int, error)
+
+// FollowerFetcher fetches the page of channel's followers starting at
+// cursor, newest first.  It returns the cursor of the next page, which is
+// empty after the last page.
+type FollowerFetcher func(channel string, cursor string) ([]Follower, string, error)
+
+// FollowerCache keeps the followers of the bot's channels in memory so
+// follower checks don't hit the API.
+type FollowerCache struct {
+	latest LatestFollowersFetcher
+	fetch  FollowerFetcher
+	clock  Clock
+
+	mu        sync.RWMutex
+	followers map[string]map[string]time.Time
+	synced    map[string]bool
+}
+
+func newFollowerCache(latest LatestFollowersFetcher, fetch FollowerFetcher, clock Clock) *FollowerCache {
+	return &FollowerCache{
+		latest:    latest,
+		fetch:     fetch,
+		clock:     clock,
+		followers: make(map[string]map[string]time.Time),
+		synced:    make(map[string]bool),
+	}
+}
+
+// IsFollower reports whether userID follows channel.
+func (c *FollowerCache) IsFollower(channel string, userID string) bool {
+	_, ok := c.FollowedSince(channel, userID)
+	return ok
+}
+
+// FollowedSince returns when userID followed channel.  Follows picked up
+// by incremental refreshes date from when they were first seen.
+func (c *FollowerCache) FollowedSince(channel string, userID string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	since, ok := c.followers[channel][userID]
+	return since, ok
+}
+
+// Count returns the number of cached followers of channel.
+func (c *FollowerCache) Count(channel string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.followers[channel])
+}
+
+// Refresh updates channel's followers.  Only the newest follows are
+// fetched unless the cache's count then disagrees with the API's, which
+// happens on the first refresh, after unfollows and when more users
+// followed than fit on a page.  Then every page is fetched and replaces
+// the cache.
+func (c *FollowerCache) Refresh(channel string) error {
+	c.mu.RLock()
+	synced := c.synced[channel]
+	c.mu.RUnlock()
+
+	if synced {
+		ids, total, err := c.latest(channel)
+		if err != nil {
+			return err
+		}
+		now := c.clock.Now()
+
+		c.mu.Lock()
+		followers := c.followers[channel]
+		for _, id := range ids {
+			if _, ok := followers[id]; !ok {
+				followers[id] = now
+			}
+		}
+		synced = len(followers) == total
+		c.mu.Unlock()
+		if synced {
+			return nil
+		}
+	}
+
+	return c.resync(channel)
+}
+
+// resync replaces channel's followers with every page of them.
+func (c *FollowerCache) resync(channel string) error {
+	fetched := make(map[string]time.Time)
+	cursor := ""
+	for {
+		page, next, err := c.fetch(channel, cursor)
+		if err != nil {
+			return err
+		}
+		for _, f := range page {
+			fetched[f.UserID] = f.Since
+		}
+		if next == "" || len(page) == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.followers[channel] = fetched
+	c.synced[channel] = true
+	return nil
+}
+
+// Followers returns the bot's follower cache.
+func (b *Bot) Followers() *FollowerCache {
+	return b.followers
+}
+
+// IsFollower reports whether userID follows channel.  The cache is
+// refreshed on a miss so new follows count right away.
+func (b *Bot) IsFollower(channel string, userID string) bool {
+	if b.followers.IsFollower(channel, userID) {
+		return true
+	}
+	err := b.followers.Refresh(channel)
+	if err != nil {
+		log.Printf("Can't refresh followers of %s: %v", channel, err)
+		return false
+	}
+	return b.followers.IsFollower(channel, userID)
+}
+
+func (b *Bot) refreshFollowers() {
+	for _, channel := range b.Channels() {
+		err := b.followers.Refresh(channel)
+		if err != nil {
+			log.Printf("Can't refresh followers of %s: %v", channel, err)
+		}
+	}
+}
+
+func (b *Bot) followerWorker() {
+	b.refreshFollowers()
+
+	ticker := b.clock.NewTicker(followerRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			b.refreshFollowers()
+		case <-b.closeC:
+			return
+		}
+	}
+}
+
+type krakenFollows struct {
+	Cursor  string `json:"_cursor"`
+	Follows []struct {
+		CreatedAt time.Time `json:"created_at"`
+		User      struct {
+			ID json.Number `json:"_id"`
+		} `json:"user"`
+	} `json:"follows"`
+}
+
+// latestFollowers is the LatestFollowersFetcher that queries the twitch
+// API.
+func (b *Bot) latestFollowers(channel string) ([]string, int, error) {
+	follows, err := b.apiClient.GetChannelFollows(channel)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ids []string
+	for _, f := range follows.Follows {
+		ids = append(ids, strconv.Itoa(f.User.ID))
+	}
+	return ids, follows.Total, nil
+}
+
+// fetchFollowers is the FollowerFetcher that queries the twitch API.  The
+// twitchapi package only returns the first page of follows.
+func (b *Bot) fetchFollowers(channel string, cursor string) ([]Follower, string, error) {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprint(followerPageSize))
+	query.Set("direction", "desc")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	var follows krakenFollows
+	err := b.apiGet("/channels/"+url.PathEscape(channel)+"/follows", query, &follows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var page []Follower
+	for _, f := range follows.Follows {
+		page = append(page, Follower{
+			UserID: f.User.ID.String(),
+			Since:  f.CreatedAt,
+		})
+	}
+	return page, follows.Cursor, nil
+}
@@ -0,0 +1,120 @@
+package roll
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/konkers/roll/clocktest"
+)
+
+// fakeFollows serves pages of followers, newest first.
+type fakeFollows struct {
+	follows  []Follower
+	pageSize int
+	fetches  int
+	latests  int
+}
+
+func (f *fakeFollows) latest(channel string) ([]string, int, error) {
+	f.latests++
+	var ids []string
+	for i := 0; i < f.pageSize && i < len(f.follows); i++ {
+		ids = append(ids, f.follows[i].UserID)
+	}
+	return ids, len(f.follows), nil
+}
+
+func (f *fakeFollows) fetch(channel string, cursor string) ([]Follower, string, error) {
+	f.fetches++
+	start := 0
+	if cursor != "" {
+		fmt.Sscan(cursor, &start)
+	}
+	end := start + f.pageSize
+	if end >= len(f.follows) {
+		return f.follows[start:], "", nil
+	}
+	return f.follows[start:end], fmt.Sprint(end), nil
+}
+
+func (f *fakeFollows) follow(userID string, since time.Time) {
+	f.follows = append([]Follower{{UserID: userID, Since: since}}, f.follows...)
+}
+
+func (f *fakeFollows) unfollow(userID string) {
+	for i, follow := range f.follows {
+		if follow.UserID == userID {
+			f.follows = append(f.follows[:i], f.follows[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *fakeFollows) expectFetches(t *testing.T, what string, latests int, fetches int) {
+	if f.latests != latests || f.fetches != fetches {
+		t.Errorf("%s fetched the newest follows %d times and %d pages instead of %d and %d",
+			what, f.latests, f.fetches, latests, fetches)
+	}
+	f.latests = 0
+	f.fetches = 0
+}
+
+func TestFollowerCache(t *testing.T) {
+	base := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := &fakeFollows{pageSize: 2}
+	for i := 0; i < 5; i++ {
+		fake.follow(fmt.Sprint(i), base.Add(time.Duration(i)*time.Hour))
+	}
+
+	clock := clocktest.New(base.Add(24 * time.Hour))
+	c := newFollowerCache(fake.latest, fake.fetch, clock)
+	if c.IsFollower("chan", "0") {
+		t.Errorf("Empty cache reports a follower")
+	}
+
+	// The first refresh fetches every page.
+	err := c.Refresh("chan")
+	if err != nil {
+		t.Fatalf("Refresh() returned error: %v", err)
+	}
+	fake.expectFetches(t, "First refresh", 0, 3)
+	if c.Count("chan") != 5 {
+		t.Errorf("Cache has %d followers instead of 5", c.Count("chan"))
+	}
+	since, ok := c.FollowedSince("chan", "3")
+	if !ok || !since.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("FollowedSince() returned %v, %v", since, ok)
+	}
+	if c.IsFollower("other", "3") {
+		t.Errorf("Follower of chan reported as follower of other")
+	}
+
+	// Later refreshes only fetch the newest follows.
+	c.Refresh("chan")
+	fake.expectFetches(t, "Refresh without changes", 1, 0)
+	fake.follow("new", base)
+	c.Refresh("chan")
+	fake.expectFetches(t, "Refresh with a new follow", 1, 0)
+	since, ok = c.FollowedSince("chan", "new")
+	if !ok || !since.Equal(clock.Now()) || c.Count("chan") != 6 {
+		t.Errorf("Refresh with a new follow returned %v, %v for it and counted %d followers",
+			since, ok, c.Count("chan"))
+	}
+
+	// Unfollows and more new follows than fit on a page fetch every page.
+	fake.unfollow("new")
+	c.Refresh("chan")
+	fake.expectFetches(t, "Refresh after an unfollow", 1, 3)
+	if c.IsFollower("chan", "new") || c.Count("chan") != 5 {
+		t.Errorf("Refresh didn't remove unfollowed user")
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		fake.follow(id, base)
+	}
+	c.Refresh("chan")
+	fake.expectFetches(t, "Refresh after many follows", 1, 4)
+	if !c.IsFollower("chan", "a") || c.Count("chan") != 8 {
+		t.Errorf("Refresh missed followers")
+	}
+}
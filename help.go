@@ -0,0 +1,87 @@
+package roll
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func (b *Bot) addBuiltinCommands() {
+	b.AddCommand("help", "Shows help for a command.", b.helpCommand, UserLevelViewer)
+	b.AddCommand("commands", "Lists the commands you can run.", b.commandsCommand, UserLevelViewer)
+}
+
+// visibleCommands returns the commands the user running cc can run.
+func (b *Bot) visibleCommands(cc *CommandContext) []CommandInfo {
+	var cmds []CommandInfo
+	for _, cmd := range b.commands.Commands(cc.UserLevel) {
+		name := strings.Fields(cmd.Name)[0]
+		if b.commandEnabled(cc, name) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+func (b *Bot) commandsCommand(cc *CommandContext, args []string) error {
+	var names []string
+	for _, cmd := range b.visibleCommands(cc) {
+		names = append(names, cc.Prefix+cmd.Name)
+	}
+	cc.Say("Commands: " + strings.Join(names, ", "))
+	return nil
+}
+
+func (b *Bot) helpCommand(cc *CommandContext, args []string) error {
+	if len(args) == 0 {
+		cc.Say(fmt.Sprintf("Type %scommands for a list of commands or %shelp <command> for help with one.",
+			cc.Prefix, cc.Prefix))
+		return nil
+	}
+
+	path := append([]string(nil), args...)
+	path[0] = strings.TrimPrefix(path[0], cc.Prefix)
+
+	cmd, ok := b.commands.Lookup(path)
+	if !ok || cmd.UserLevel > cc.UserLevel || !b.commandEnabled(cc, path[0]) {
+		cc.Say(fmt.Sprintf("There's no %s%s command.", cc.Prefix, strings.Join(path, " ")))
+		return nil
+	}
+
+	cc.Say(fmt.Sprintf("%s%s - %s", cc.Prefix, cmd.Name, cmd.Help))
+	return nil
+}
+
+func (b *Bot) commandsHandler(w http.ResponseWriter, req *http.Request) {
+	commandsTemplate, err := b.getTemplate("commands.html")
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, req)
+		return
+	}
+
+	channel := req.FormValue("channel")
+	if channel == "" {
+		channel = b.DefaultChannel()
+	}
+	channel = strings.ToLower(channel)
+
+	// The page is public, so it only lists what any viewer can run.
+	cc := &CommandContext{
+		Bot:       b,
+		Channel:   channel,
+		UserLevel: UserLevelViewer,
+	}
+	data := struct {
+		Prefix   string
+		Commands []CommandInfo
+	}{
+		Prefix:   b.CommandPrefix(channel),
+		Commands: b.visibleCommands(cc),
+	}
+	err = commandsTemplate.Execute(w, data)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,150 @@
+package roll
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newHelpTestEngine() *CmdEngine {
+	command := func(cc *CommandContext, args []string) error {
+		return nil
+	}
+
+	sub := NewCmdEngine()
+	sub.AddCommand("next", "next help", command, UserLevelModerator)
+	sub.AddCommand("status", "status help", command, UserLevelViewer)
+
+	engine := NewCmdEngine()
+	engine.AddCommand("game", "game help", command, UserLevelViewer)
+	engine.AddCommand("setgame", "setgame help", command, UserLevelModerator)
+	engine.AddCommand("marathon", "marathon help", command, UserLevelViewer)
+	engine.AddSubEngine("marathon", sub)
+	return engine
+}
+
+func commandNames(cmds []CommandInfo) []string {
+	var names []string
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+func TestCmdEngineCommands(t *testing.T) {
+	engine := newHelpTestEngine()
+
+	names := commandNames(engine.Commands(UserLevelViewer))
+	expected := []string{"game", "marathon", "marathon status"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Viewer commands %v != %v", names, expected)
+	}
+
+	names = commandNames(engine.Commands(UserLevelModerator))
+	expected = []string{"game", "marathon", "marathon next", "marathon status", "setgame"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Moderator commands %v != %v", names, expected)
+	}
+
+	err := engine.AddSubEngine("nothing", NewCmdEngine())
+	if err == nil {
+		t.Errorf("AddSubEngine() on a missing command did not return an error")
+	}
+}
+
+func TestCmdEngineLookup(t *testing.T) {
+	engine := newHelpTestEngine()
+
+	tests := []struct {
+		path []string
+		ok   bool
+		cmd  CommandInfo
+	}{
+		{[]string{"game"}, true, CommandInfo{"game", "game help", UserLevelViewer}},
+		{[]string{"marathon", "next"}, true, CommandInfo{"marathon next", "next help", UserLevelModerator}},
+		{[]string{"marathon", "nope"}, false, CommandInfo{}},
+		{[]string{"game", "next"}, false, CommandInfo{}},
+		{[]string{}, false, CommandInfo{}},
+	}
+
+	for _, test := range tests {
+		cmd, ok := engine.Lookup(test.path)
+		if ok != test.ok || cmd != test.cmd {
+			t.Errorf("Lookup(%v) returned (%v, %v) instead of (%v, %v)",
+				test.path, cmd, ok, test.cmd, test.ok)
+		}
+	}
+}
+
+func TestHelpCommands(t *testing.T) {
+	b, _ := newTestBot(t)
+	command := func(cc *CommandContext, args []string) error {
+		return nil
+	}
+	b.AddCommand("game", "game help", command, UserLevelViewer)
+	b.AddCommand("setgame", "setgame help", command, UserLevelModerator)
+
+	cc := &CommandContext{
+		Bot:     b,
+		Channel: "testchan",
+		Prefix:  "!",
+	}
+
+	tests := []struct {
+		handler func(*CommandContext, []string) error
+		args    []string
+		reply   string
+	}{
+		{b.commandsCommand, nil, "Commands: !commands, !game, !help"},
+		{b.helpCommand, []string{"game"}, "!game - game help"},
+		{b.helpCommand, []string{"!game"}, "!game - game help"},
+		{b.helpCommand, []string{"setgame"}, "There's no !setgame command."},
+	}
+
+	for _, test := range tests {
+		args := append([]string(nil), test.args...)
+		err := test.handler(cc, args)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Handler changed its args from %v to %v", test.args, args)
+		}
+		msg := b.outQ.pop()
+		if msg == nil || msg.text != test.reply {
+			t.Errorf("Got reply %v instead of \"%s\"", msg, test.reply)
+		}
+	}
+}
+
+func TestCommandsPage(t *testing.T) {
+	bot, _ := newConnectedTestBot(t)
+	command := func(cc *CommandContext, args []string) error {
+		return nil
+	}
+	bot.AddCommand("game", "game help", command, UserLevelViewer)
+	bot.AddCommand("setgame", "setgame help", command, UserLevelModerator)
+
+	client := getTestHttpClient()
+	url := "https://" + bot.Config.HTTPSAddr + "/commands?channel=testchan"
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Got error getting %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Got status %s for %s", resp.Status, url)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Can't read %s: %v", url, err)
+	}
+	if !strings.Contains(string(body), "!game") {
+		t.Errorf("Commands page doesn't list !game")
+	}
+	if strings.Contains(string(body), "!setgame") {
+		t.Errorf("Commands page lists moderator command !setgame")
+	}
+}
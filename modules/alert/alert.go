@@ -1,9 +1,14 @@
 package alert
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
-	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/asdine/storm"
@@ -12,21 +17,92 @@ import (
 
 type Alert struct {
 	ID        int           `json:"id" storm:"id,increment"`
+	Channel   string        `json:"channel" storm:"index"`
 	Period    roll.Duration `json:"period"`
 	NextAlert roll.Time     `json:"next_alert"`
 	Message   string        `json:"message"`
+
+	// Chat lines that must be seen since the alert last fired.
+	MinLines int `json:"min_lines"`
+	// Alerts in the same group share a schedule and take turns.
+	Group string `json:"group"`
+	// Only fire while the stream is live.
+	OnlineOnly bool `json:"online_only"`
+
+	// A cron expression, evaluated in TimeZone, used instead of Period.
+	Cron     string `json:"cron"`
+	TimeZone string `json:"time_zone"`
+	// A bot command, without prefix, to run instead of saying Message.
+	Command string `json:"command"`
+	// What to do about runs missed while the bot was down: CatchUpOnce
+	// (the default) or CatchUpSkip.
+	CatchUp string `json:"catch_up"`
+}
+
+const (
+	CatchUpOnce = "once"
+	CatchUpSkip = "skip"
+)
+
+// nextAlert returns when alert fires after t.
+func nextAlert(alert *Alert, t time.Time) (time.Time, error) {
+	if alert.Cron == "" {
+		if alert.Period.Duration <= 0 {
+			return time.Time{}, fmt.Errorf("alert has no period or cron schedule")
+		}
+		return t.Add(alert.Period.Duration), nil
+	}
+
+	sched, err := roll.ParseCron(alert.Cron, alert.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := sched.Next(t)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron schedule \"%s\" never fires", alert.Cron)
+	}
+	return next, nil
+}
+
+// prepare checks alert's schedule and sets its first run after now if it
+// doesn't have one.
+func prepare(alert *Alert, now time.Time) error {
+	switch alert.CatchUp {
+	case "", CatchUpOnce, CatchUpSkip:
+	default:
+		return fmt.Errorf("unknown catch up policy %s", alert.CatchUp)
+	}
+
+	next, err := nextAlert(alert, now)
+	if err != nil {
+		return err
+	}
+	if alert.NextAlert.IsZero() {
+		alert.NextAlert.Time = next
+	}
+	return nil
 }
 
 type AlertModule struct {
 	bot *roll.Bot
 	db  storm.Node
 
-	service *AlertService
-	closeC  chan struct{}
-}
+	alertCmd *roll.CmdEngine
+	service  *AlertService
+	closeC   chan struct{}
+	// When the module started.  Runs due before then were missed.
+	started time.Time
 
-type AlertService struct {
-	module *AlertModule
+	// Alerts are held while disconnected so they aren't lost.
+	mu        sync.Mutex
+	connected bool
+	// Chat lines seen in each channel.
+	lines map[string]int
+	// Lines seen in the channel when each schedule last fired, keyed by
+	// scheduleKey.
+	firedLines map[string]int
+	// Last alert fired in each rotation group, keyed by scheduleKey.
+	lastFired map[string]int
 }
 
 func init() {
@@ -35,24 +111,44 @@ func init() {
 
 func NewAlertModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
 	module := &AlertModule{
-		bot:    bot,
-		db:     dbBucket,
-		closeC: make(chan struct{}),
+		bot:        bot,
+		db:         dbBucket,
+		alertCmd:   roll.NewCmdEngine(),
+		closeC:     make(chan struct{}),
+		lines:      make(map[string]int),
+		firedLines: make(map[string]int),
+		lastFired:  make(map[string]int),
 	}
-
 	module.service = NewAlertService(module)
 
-	return module, nil
-}
+	module.alertCmd.AddCommand("list", "list alerts", module.alertListCommand, roll.UserLevelModerator)
+	module.alertCmd.AddCommand("add", "add an alert: [-lines n] [-group name] [-online] <period> <message>", module.alertAddCommand, roll.UserLevelModerator)
+	module.alertCmd.AddCommand("set", "change alert <id>: lines <n>, group [name] or online on|off", module.alertSetCommand, roll.UserLevelModerator)
+	module.alertCmd.AddCommand("del", "delete alert <id>", module.alertDelCommand, roll.UserLevelModerator)
+	module.alertCmd.AddCommand("trigger", "say alert <id> now", module.alertTriggerCommand, roll.UserLevelModerator)
+
+	err := bot.AddCommand("alert", "Manages timed messages.", module.alertCommand, roll.UserLevelModerator)
+	if err != nil {
+		return nil, err
+	}
+	bot.AddSubEngine("alert", module.alertCmd)
 
-func NewAlertService(module *AlertModule) *AlertService {
-	s := &AlertService{
-		module: module,
+	// Alerts saved before multiple channel support belong to the default
+	// channel.
+	var alerts []Alert
+	module.db.All(&alerts)
+	for _, alert := range alerts {
+		if alert.Channel == "" {
+			alert.Channel = bot.DefaultChannel()
+			module.db.Save(&alert)
+		}
 	}
-	return s
+
+	return module, nil
 }
 
 func (m *AlertModule) Start() error {
+	m.started = m.bot.Clock().Now()
 	go m.worker()
 	return nil
 }
@@ -62,7 +158,150 @@ func (m *AlertModule) Stop() error {
 	return nil
 }
 
+func (m *AlertModule) GetRPCService() interface{} {
+	return m.service
+}
+
+func (m *AlertModule) ConnectionStateChanged(state roll.ConnectionState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected = state == roll.ConnectionConnected
+}
+
+func (m *AlertModule) isConnected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.connected
+}
+
+// HandleEvent counts chat lines for alerts' MinLines.
+func (m *AlertModule) HandleEvent(event *roll.Event) {
+	if event.Type != roll.EventMessage || event.Command ||
+		strings.EqualFold(event.Username, m.bot.Config.BotUsername) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lines[event.Channel]++
+}
+
+// scheduleKey identifies the schedule an alert fires on.  Grouped alerts
+// share one.
+func scheduleKey(alert *Alert) string {
+	if alert.Group == "" {
+		return fmt.Sprintf("%s/#%d", alert.Channel, alert.ID)
+	}
+	return alert.Channel + "/" + alert.Group
+}
+
+// linesSince returns the chat lines seen since schedule key last fired.
+func (m *AlertModule) linesSince(channel string, key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lines[channel] - m.firedLines[key]
+}
+
+// schedule is a set of alerts that fire together, one at a time.
+type schedule struct {
+	key    string
+	alerts []*Alert
+}
+
+// schedules groups alerts into their schedules, with alerts in ID order.
+func schedules(alerts []Alert) []*schedule {
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+
+	var scheds []*schedule
+	byKey := make(map[string]*schedule)
+	for i := range alerts {
+		key := scheduleKey(&alerts[i])
+		s, ok := byKey[key]
+		if !ok {
+			s = &schedule{key: key}
+			byKey[key] = s
+			scheds = append(scheds, s)
+		}
+		s.alerts = append(s.alerts, &alerts[i])
+	}
+	return scheds
+}
+
+// due reports whether any alert in s is due at t.
+func (s *schedule) due(t time.Time) bool {
+	for _, alert := range s.alerts {
+		if alert.NextAlert.Before(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the alert after the one that fired last.
+func (s *schedule) next(last int) *Alert {
+	for _, alert := range s.alerts {
+		if alert.ID > last {
+			return alert
+		}
+	}
+	return s.alerts[0]
+}
+
+// fire says alert or runs its command at userLevel, and reschedules its
+// schedule.
+func (m *AlertModule) fire(alert *Alert, sched *schedule, t time.Time, userLevel int) error {
+	var err error
+	if alert.Command != "" {
+		log.Printf("Running \"%s\" in %s", alert.Command, alert.Channel)
+		err = m.bot.RunCommandAs(alert.Channel, alert.Command, userLevel)
+		if err != nil {
+			log.Printf("Can't run alert %d: %v", alert.ID, err)
+		}
+	} else {
+		log.Printf("Saying \"%s\" in %s", alert.Message, alert.Channel)
+		m.bot.Say(alert.Channel, alert.Message)
+	}
+
+	m.mu.Lock()
+	m.firedLines[sched.key] = m.lines[alert.Channel]
+	m.lastFired[sched.key] = alert.ID
+	m.mu.Unlock()
+
+	m.reschedule(alert, sched, t)
+	return err
+}
+
+// reschedule sets the next run of every alert in sched from alert's
+// schedule.
+func (m *AlertModule) reschedule(alert *Alert, sched *schedule, t time.Time) {
+	next, err := nextAlert(alert, t)
+	if err != nil {
+		log.Printf("Can't schedule alert %d: %v", alert.ID, err)
+		return
+	}
+	for _, a := range sched.alerts {
+		a.NextAlert.Time = next
+		err := m.db.Save(a)
+		if err != nil {
+			log.Printf("Can't save alert %d: %v", a.ID, err)
+		}
+	}
+}
+
+// missed reports whether sched's run was missed while the bot was down.
+func (s *schedule) missed(started time.Time) bool {
+	for _, alert := range s.alerts {
+		if alert.NextAlert.Before(started) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *AlertModule) tick(t time.Time) {
+	if !m.isConnected() {
+		return
+	}
+
 	var alerts []Alert
 	err := m.db.All(&alerts)
 	if err != nil {
@@ -70,59 +309,313 @@ func (m *AlertModule) tick(t time.Time) {
 		return
 	}
 
-	for _, alert := range alerts {
-		if alert.NextAlert.Before(t) {
-			log.Printf("Saying \"%s\"", alert.Message)
-			m.bot.Irc().Say(m.bot.Config.Channel, alert.Message)
-			alert.NextAlert.Time = t.Add(alert.Period.Duration)
-			m.db.Save(&alert)
+	for _, sched := range schedules(alerts) {
+		if !sched.due(t) {
+			continue
+		}
+
+		m.mu.Lock()
+		alert := sched.next(m.lastFired[sched.key])
+		m.mu.Unlock()
+
+		if !m.bot.ModuleEnabled(alert.Channel, m) {
+			continue
+		}
+		if alert.CatchUp == CatchUpSkip && sched.missed(m.started) {
+			log.Printf("Skipping missed alert %d", alert.ID)
+			m.reschedule(alert, sched, t)
+			continue
+		}
+		if m.linesSince(alert.Channel, sched.key) < alert.MinLines {
+			continue
+		}
+		if alert.OnlineOnly {
+			online, err := m.bot.StreamOnline(alert.Channel)
+			if err != nil {
+				log.Printf("Can't get stream status of %s: %v", alert.Channel, err)
+				continue
+			}
+			if !online {
+				continue
+			}
 		}
+
+		m.fire(alert, sched, t, roll.UserLevelBroadcaster)
 	}
 }
 
 func (m *AlertModule) worker() {
+	ticker := m.bot.Clock().NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
 	for {
-		ticker := time.NewTicker(1 * time.Minute)
 		select {
-		case t := <-ticker.C:
+		case t := <-ticker.C():
 			m.tick(t)
-		case _, ok := <-m.closeC:
-			if !ok {
-				return
+		case <-m.closeC:
+			return
+		}
+	}
+}
+
+// Trigger says alert id now, regardless of its schedule.
+func (m *AlertModule) Trigger(id int) error {
+	return m.trigger(id, roll.UserLevelBroadcaster)
+}
+
+// trigger fires alert id now, running its command at userLevel.
+func (m *AlertModule) trigger(id int, userLevel int) error {
+	var alert Alert
+	err := m.db.One("ID", id, &alert)
+	if err != nil {
+		return err
+	}
+
+	var alerts []Alert
+	err = m.db.Find("Channel", alert.Channel, &alerts)
+	if err != nil {
+		return err
+	}
+	for _, sched := range schedules(alerts) {
+		if sched.key != scheduleKey(&alert) {
+			continue
+		}
+		for _, a := range sched.alerts {
+			if a.ID == id {
+				return m.fire(a, sched, m.bot.Clock().Now(), userLevel)
 			}
 		}
 	}
+	return storm.ErrNotFound
 }
 
-func (s *AlertService) New(r *http.Request, alert *Alert, id *int) error {
-	alert.ID = 0
-	return s.Update(r, alert, id)
+func (m *AlertModule) channelAlerts(channel string) ([]Alert, error) {
+	var alerts []Alert
+	err := m.db.Find("Channel", channel, &alerts)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+	return alerts, err
 }
 
-func (s *AlertService) Update(r *http.Request, alert *Alert, id *int) error {
-	if !s.module.bot.IsAdminRequest(r) {
-		return fmt.Errorf("access denied")
+func (m *AlertModule) alertCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) == 0 {
+		return m.alertListCommand(cc, args)
 	}
-	err := s.module.db.Save(alert)
+	return m.alertCmd.Exec(cc, cc.UserLevel, args)
+}
+
+func (m *AlertModule) alertListCommand(cc *roll.CommandContext, args []string) error {
+	alerts, err := m.channelAlerts(cc.Channel)
 	if err != nil {
-		*id = -1
 		return err
 	}
+	if len(alerts) == 0 {
+		cc.Say("There are no alerts.")
+		return nil
+	}
 
-	*id = alert.ID
+	for _, alert := range alerts {
+		cc.Bot.SayPriority(cc.Channel, describe(&alert, cc.Prefix), roll.PriorityLow)
+	}
 	return nil
 }
 
-func (s *AlertService) Get(r *http.Request, id *int, alert *Alert) error {
-	return s.module.db.One("ID", *id, alert)
+// describe summarizes alert's schedule, conditions and action for chat.
+func describe(alert *Alert, prefix string) string {
+	desc := fmt.Sprintf("#%d", alert.ID)
+	if alert.Cron != "" {
+		desc += fmt.Sprintf(" at \"%s\"", alert.Cron)
+		if alert.TimeZone != "" {
+			desc += " " + alert.TimeZone
+		}
+	} else {
+		desc += fmt.Sprintf(" every %s", alert.Period.Duration)
+	}
+	if alert.Group != "" {
+		desc += fmt.Sprintf(" in %s", alert.Group)
+	}
+	if alert.MinLines > 0 {
+		desc += fmt.Sprintf(" after %d lines", alert.MinLines)
+	}
+	if alert.OnlineOnly {
+		desc += " while live"
+	}
+
+	if alert.Command != "" {
+		return fmt.Sprintf("%s: runs %s%s", desc, prefix, alert.Command)
+	}
+	return fmt.Sprintf("%s: %s", desc, alert.Message)
 }
 
-func (s *AlertService) Trigger(r *http.Request, id *int, resp *int) error {
+func (m *AlertModule) alertAddCommand(cc *roll.CommandContext, args []string) error {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	lines := flags.Int("lines", 0, "chat lines needed between runs")
+	group := flags.String("group", "", "group to take turns with")
+	online := flags.Bool("online", false, "only while live")
+	err := flags.Parse(args)
+	args = flags.Args()
+	if err != nil || len(args) < 2 || *lines < 0 {
+		cc.Say(fmt.Sprintf("Usage: %salert add [-lines n] [-group name] [-online] <period> <message>",
+			cc.Prefix))
+		return nil
+	}
+	period, err := time.ParseDuration(args[0])
+	if err != nil || period < time.Minute {
+		cc.Say(fmt.Sprintf("%s is not a period of at least 1m.", args[0]))
+		return nil
+	}
+
+	alert := &Alert{
+		Channel:    cc.Channel,
+		Period:     roll.Duration{Duration: period},
+		Message:    strings.Join(args[1:], " "),
+		MinLines:   *lines,
+		Group:      *group,
+		OnlineOnly: *online,
+	}
+	err = prepare(alert, m.bot.Clock().Now())
+	if err != nil {
+		return err
+	}
+	err = m.db.Save(alert)
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("Added alert #%d.", alert.ID))
+	return nil
+}
+
+// parseID parses an alert id argument.
+func parseID(cc *roll.CommandContext, args []string, usage string) (int, bool) {
+	if len(args) != 1 {
+		cc.Say(fmt.Sprintf("Usage: %salert %s <id>", cc.Prefix, usage))
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s is not an alert id.", args[0]))
+		return 0, false
+	}
+	return id, true
+}
+
+// channelAlert returns alert id if it belongs to channel.
+func (m *AlertModule) channelAlert(channel string, id int) (*Alert, error) {
 	var alert Alert
-	err := s.module.db.One("ID", *id, alert)
+	err := m.db.One("ID", id, &alert)
+	if err != nil {
+		return nil, err
+	}
+	if alert.Channel != channel {
+		return nil, storm.ErrNotFound
+	}
+	return &alert, nil
+}
+
+func (m *AlertModule) alertDelCommand(cc *roll.CommandContext, args []string) error {
+	id, ok := parseID(cc, args, "del")
+	if !ok {
+		return nil
+	}
+
+	alert, err := m.channelAlert(cc.Channel, id)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no alert #%d.", id))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = m.db.DeleteStruct(alert)
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("Deleted alert #%d.", id))
+	return nil
+}
+
+func (m *AlertModule) alertSetCommand(cc *roll.CommandContext, args []string) error {
+	usage := fmt.Sprintf("Usage: %salert set <id> lines <n> | group [name] | online on|off", cc.Prefix)
+	if len(args) < 2 {
+		cc.Say(usage)
+		return nil
+	}
+	id, ok := parseID(cc, args[:1], "set")
+	if !ok {
+		return nil
+	}
+
+	alert, err := m.channelAlert(cc.Channel, id)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no alert #%d.", id))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	value := strings.Join(args[2:], " ")
+	switch args[1] {
+	case "lines":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			cc.Say(fmt.Sprintf("%s is not a number of lines.", value))
+			return nil
+		}
+		alert.MinLines = n
+	case "group":
+		alert.Group = value
+	case "online":
+		switch value {
+		case "on":
+			alert.OnlineOnly = true
+		case "off":
+			alert.OnlineOnly = false
+		default:
+			cc.Say(usage)
+			return nil
+		}
+	default:
+		cc.Say(usage)
+		return nil
+	}
+
+	err = m.db.Save(alert)
 	if err != nil {
 		return err
 	}
-	alert.NextAlert.Time = time.Now()
-	return s.module.db.Save(alert)
+	cc.Say(describe(alert, cc.Prefix))
+	return nil
+}
+
+func (m *AlertModule) alertTriggerCommand(cc *roll.CommandContext, args []string) error {
+	id, ok := parseID(cc, args, "trigger")
+	if !ok {
+		return nil
+	}
+
+	alert, err := m.channelAlert(cc.Channel, id)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no alert #%d.", id))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Triggering can't run commands above the user's level.
+	level := cc.UserLevel
+	if level > roll.UserLevelBroadcaster {
+		level = roll.UserLevelBroadcaster
+	}
+	err = m.trigger(id, level)
+	if err != nil && alert.Command != "" {
+		cc.Say(fmt.Sprintf("Can't run alert #%d: %v", id, err))
+		return nil
+	}
+	return err
 }
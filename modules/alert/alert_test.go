@@ -0,0 +1,181 @@
+package alert
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/konkers/roll"
+	"github.com/konkers/roll/clocktest"
+	"github.com/konkers/roll/rolltest"
+)
+
+const channel = rolltest.Channel
+
+type alertTest struct {
+	t     *testing.T
+	clock *clocktest.Clock
+	bot   *roll.Bot
+	m     *AlertModule
+}
+
+func newAlertTest(t *testing.T) *alertTest {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	bot := rolltest.NewBot(t, clock)
+	a := &alertTest{
+		t:     t,
+		clock: clock,
+		bot:   bot,
+		m:     rolltest.AddModule(t, bot, "alert").(*AlertModule),
+	}
+	// The test ticks the module itself instead of starting its worker.
+	a.m.started = clock.Now()
+	a.m.ConnectionStateChanged(roll.ConnectionConnected)
+	return a
+}
+
+func (a *alertTest) close() {
+	rolltest.Close(a.bot)
+}
+
+func (a *alertTest) add(alert *Alert) {
+	alert.Channel = channel
+	err := prepare(alert, a.clock.Now())
+	if err != nil {
+		a.t.Fatalf("prepare() returned error: %v", err)
+	}
+	err = a.m.db.Save(alert)
+	if err != nil {
+		a.t.Fatalf("Can't save alert: %v", err)
+	}
+}
+
+// run advances the clock by d, ticking every minute like the worker, and
+// returns the messages said.
+func (a *alertTest) run(d time.Duration) []string {
+	for end := a.clock.Now().Add(d); a.clock.Now().Before(end); {
+		a.clock.Advance(time.Minute)
+		a.m.tick(a.clock.Now())
+	}
+	return a.bot.TakeMessages(channel)
+}
+
+func (a *alertTest) chat(lines int) {
+	for i := 0; i < lines; i++ {
+		a.m.HandleEvent(&roll.Event{
+			Type:     roll.EventMessage,
+			Channel:  channel,
+			Username: "alice",
+		})
+	}
+}
+
+func (a *alertTest) expect(what string, got []string, want ...string) {
+	if !reflect.DeepEqual(got, want) {
+		a.t.Errorf("%s said %q instead of %q", what, got, want)
+	}
+}
+
+func TestAlertMinLines(t *testing.T) {
+	a := newAlertTest(t)
+	defer a.close()
+
+	a.add(&Alert{
+		Period:   roll.Duration{Duration: 5 * time.Minute},
+		Message:  "hi",
+		MinLines: 2,
+	})
+	a.expect("Quiet chat", a.run(10*time.Minute))
+	a.chat(1)
+	a.expect("One line", a.run(time.Minute))
+	a.chat(1)
+	a.expect("Two lines", a.run(time.Minute), "hi")
+	a.expect("Quiet chat after firing", a.run(10*time.Minute))
+	a.chat(2)
+	a.expect("Two more lines", a.run(time.Minute), "hi")
+}
+
+func TestAlertGroups(t *testing.T) {
+	a := newAlertTest(t)
+	defer a.close()
+
+	for _, msg := range []string{"one", "two"} {
+		a.add(&Alert{
+			Period:  roll.Duration{Duration: 5 * time.Minute},
+			Message: msg,
+			Group:   "g",
+		})
+	}
+	a.add(&Alert{
+		Period:  roll.Duration{Duration: 5 * time.Minute},
+		Message: "three",
+	})
+
+	// Grouped alerts take turns on one schedule.  Others keep their own.
+	a.expect("20 minutes", a.run(20*time.Minute),
+		"one", "three", "two", "three", "one", "three")
+}
+
+func TestAlertCatchUp(t *testing.T) {
+	a := newAlertTest(t)
+	defer a.close()
+
+	// Both alerts missed two runs while the bot was down.
+	missed := roll.Time{Time: a.clock.Now().Add(-time.Hour)}
+	a.add(&Alert{
+		Period:    roll.Duration{Duration: 30 * time.Minute},
+		NextAlert: missed,
+		Message:   "once",
+	})
+	a.add(&Alert{
+		Period:    roll.Duration{Duration: 30 * time.Minute},
+		NextAlert: missed,
+		Message:   "skip",
+		CatchUp:   CatchUpSkip,
+	})
+
+	a.expect("First tick", a.run(time.Minute), "once")
+	a.expect("After catching up", a.run(31*time.Minute), "once", "skip")
+}
+
+func TestAlertCommandLevel(t *testing.T) {
+	a := newAlertTest(t)
+	defer a.close()
+
+	ran := 0
+	a.bot.AddCommand("secret", "test", func(cc *roll.CommandContext, args []string) error {
+		ran++
+		return nil
+	}, roll.UserLevelBroadcaster)
+	a.add(&Alert{
+		Period:  roll.Duration{Duration: time.Hour},
+		Command: "secret",
+	})
+
+	// Moderators can't trigger commands they can't run.
+	mod := &roll.CommandContext{
+		Bot:       a.bot,
+		Channel:   channel,
+		User:      &twitch.User{Username: "mod"},
+		UserLevel: roll.UserLevelModerator,
+		Prefix:    "!",
+	}
+	a.m.alertTriggerCommand(mod, []string{"1"})
+	if ran != 0 {
+		t.Errorf("Moderator triggered a broadcaster command")
+	}
+
+	broadcaster := *mod
+	broadcaster.UserLevel = roll.UserLevelBroadcaster
+	a.m.alertTriggerCommand(&broadcaster, []string{"1"})
+	if ran != 1 {
+		t.Errorf("Broadcaster's trigger ran the command %d times", ran)
+	}
+
+	// Scheduled runs are the broadcaster's.
+	a.run(61 * time.Minute)
+	if ran != 2 {
+		t.Errorf("Scheduled alert ran the command %d times", ran-1)
+	}
+}
@@ -0,0 +1,94 @@
+package alert
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type AlertService struct {
+	module *AlertModule
+}
+
+type AlertList struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+func NewAlertService(module *AlertModule) *AlertService {
+	s := &AlertService{
+		module: module,
+	}
+	return s
+}
+
+func (s *AlertService) channel(channel string) string {
+	if channel == "" {
+		return s.module.bot.DefaultChannel()
+	}
+	return channel
+}
+
+func (s *AlertService) New(r *http.Request, alert *Alert, id *int) error {
+	alert.ID = 0
+	return s.Update(r, alert, id)
+}
+
+func (s *AlertService) Update(r *http.Request, alert *Alert, id *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	if alert.Channel == "" {
+		alert.Channel = s.module.bot.DefaultChannel()
+	}
+	err := prepare(alert, s.module.bot.Clock().Now())
+	if err != nil {
+		*id = -1
+		return err
+	}
+	err = s.module.db.Save(alert)
+	if err != nil {
+		*id = -1
+		return err
+	}
+
+	*id = alert.ID
+	return nil
+}
+
+func (s *AlertService) Get(r *http.Request, id *int, alert *Alert) error {
+	return s.module.db.One("ID", *id, alert)
+}
+
+func (s *AlertService) Del(r *http.Request, id *int, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	var alert Alert
+	err := s.module.db.One("ID", *id, &alert)
+	if err != nil {
+		return err
+	}
+	err = s.module.db.DeleteStruct(&alert)
+	if err != nil {
+		return err
+	}
+	*ret = *id
+	return nil
+}
+
+func (s *AlertService) All(r *http.Request, channel *string, list *AlertList) error {
+	var err error
+	list.Alerts, err = s.module.channelAlerts(s.channel(*channel))
+	return err
+}
+
+func (s *AlertService) Trigger(r *http.Request, id *int, resp *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.module.Trigger(*id)
+	if err != nil {
+		return err
+	}
+	*resp = *id
+	return nil
+}
@@ -1,8 +1,9 @@
-package roll
+package game
 
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/asdine/storm"
 	"github.com/konkers/roll"
@@ -11,11 +12,16 @@ import (
 type GameModule struct {
 }
 
+func init() {
+	roll.RegisterModuleFactory(NewGameModule, "game")
+}
+
 func NewGameModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
 	module := &GameModule{}
 
-	bot.AddCommand("game", "Lists the current game.", module.gameCommand, 0)
-	bot.AddCommand("setgame", "Sets the current game.", module.setGameCommand, 10)
+	bot.AddCommandWithCooldown("game", "Lists the current game.", module.gameCommand, 0,
+		roll.Cooldown{Global: 30 * time.Second})
+	bot.AddCommand("setgame", "Sets the current game.", module.setGameCommand, roll.UserLevelModerator)
 
 	return module, nil
 }
@@ -42,7 +48,7 @@ func (m *GameModule) gameCommand(cc *roll.CommandContext, args []string) error {
 		return err
 	}
 
-	cc.IRC.Say(cc.Channel, gameResponse(channel.Game))
+	cc.Say(gameResponse(channel.Game))
 	return nil
 }
 
@@ -58,7 +64,7 @@ func (m *GameModule) setGameCommand(cc *roll.CommandContext, args []string) erro
 	if err != nil {
 		return err
 	}
-	cc.IRC.Say(cc.Channel, setGameResponse(game))
+	cc.Say(setGameResponse(game))
 
 	return nil
 }
@@ -1,26 +1,144 @@
 package giveaway
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/asdine/storm"
 	"github.com/konkers/roll"
+	"github.com/konkers/roll/modules/points"
 )
 
+const defaultClaimTimeout = 2 * time.Minute
+
+type Winner struct {
+	Username string    `json:"username"`
+	Drawn    time.Time `json:"drawn"`
+	Claimed  bool      `json:"claimed"`
+	// Expired is set when the winner didn't claim in time.
+	Expired bool `json:"expired"`
+}
+
+// Entry is a participant's standing in a giveaway.
+type Entry struct {
+	Subscriber bool `json:"subscriber"`
+	// Extra tickets bought with points.
+	Tickets int `json:"tickets"`
+}
+
 type Giveaway struct {
 	ID           int      `json:"id" storm:"id,increment"`
+	Channel      string   `json:"channel" storm:"index"`
 	Tag          string   `json:"tag"`
 	Desc         string   `json:"desc"`
 	Participants []string `json:"participants"`
+
+	// Entries are only accepted while the giveaway isn't closed and, if
+	// set, between StartTime and EndTime.
+	Closed    bool      `json:"closed"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+
+	// How long a winner has to claim before another is drawn.  Zero
+	// uses defaultClaimTimeout.
+	ClaimTimeout roll.Duration `json:"claim_timeout"`
+	Winners      []Winner      `json:"winners"`
+
+	// Entries are keyed by participant.  Participants without one have a
+	// single ticket.
+	Entries map[string]Entry `json:"entries"`
+
+	// Weighting.  Every participant starts with one ticket.  Zero values
+	// disable each option.
+	//
+	// Subscribers' tickets are multiplied by SubscriberMultiplier.
+	SubscriberMultiplier int `json:"subscriber_multiplier"`
+	// Extra tickets cost TicketCost points each.
+	TicketCost int `json:"ticket_cost"`
+	// One bonus ticket is given for each WatchTimeBonus of watch time.
+	WatchTimeBonus roll.Duration `json:"watch_time_bonus"`
+	// No one gets more than MaxTickets tickets.
+	MaxTickets int `json:"max_tickets"`
+}
+
+// Odds are a participant's chances of being drawn.
+type Odds struct {
+	Username string  `json:"username"`
+	Tickets  int     `json:"tickets"`
+	Percent  float64 `json:"percent"`
+}
+
+// Accepting reports whether g takes entries at time t.
+func (g *Giveaway) Accepting(t time.Time) bool {
+	if g.Closed {
+		return false
+	}
+	if !g.StartTime.IsZero() && t.Before(g.StartTime) {
+		return false
+	}
+	if !g.EndTime.IsZero() && !t.Before(g.EndTime) {
+		return false
+	}
+	return true
+}
+
+// PendingWinner returns the drawn winner who hasn't claimed yet.
+func (g *Giveaway) PendingWinner() *Winner {
+	if len(g.Winners) == 0 {
+		return nil
+	}
+	w := &g.Winners[len(g.Winners)-1]
+	if w.Claimed || w.Expired {
+		return nil
+	}
+	return w
+}
+
+func (g *Giveaway) hasParticipant(username string) bool {
+	for _, p := range g.Participants {
+		if p == username {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Giveaway) hasWon(username string) bool {
+	for _, w := range g.Winners {
+		if w.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Giveaway) claimTimeout() time.Duration {
+	if g.ClaimTimeout.Duration > 0 {
+		return g.ClaimTimeout.Duration
+	}
+	return defaultClaimTimeout
 }
 
 type GiveawayModule struct {
 	bot *roll.Bot
 	db  storm.Node
 
-	service *GiveawayService
+	giveawayCmd *roll.CmdEngine
+	service     *GiveawayService
+
+	// Serializes draws and claims.
+	mu sync.Mutex
+	// Claim timeouts keyed by giveaway ID.
+	claimTimers map[int]roll.Timer
 }
 
 func init() {
@@ -29,21 +147,68 @@ func init() {
 
 func NewGiveawayModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
 	module := &GiveawayModule{
-		bot: bot,
-		db:  dbBucket,
+		bot:         bot,
+		db:          dbBucket,
+		giveawayCmd: roll.NewCmdEngine(),
+		claimTimers: make(map[int]roll.Timer),
 	}
 	module.service = NewGiveawayService(module)
 
-	bot.AddCommand("giveaway", "Giveaway command", module.giveawayCommand, 0)
+	module.giveawayCmd.AddCommand("open", "open entries for <tag>", module.giveawayOpenCommand, roll.UserLevelModerator)
+	module.giveawayCmd.AddCommand("close", "close entries for <tag>", module.giveawayCloseCommand, roll.UserLevelModerator)
+	module.giveawayCmd.AddCommand("draw", "draw a winner for <tag>", module.giveawayDrawCommand, roll.UserLevelModerator)
+	module.giveawayCmd.AddCommand("claim", "claim your prize", module.giveawayClaimCommand, 0)
+	module.giveawayCmd.AddCommand("buy", "buy <n> extra tickets for <tag> with points", module.giveawayBuyCommand, 0)
+
+	err := bot.AddCommand("giveaway", "Lists giveaways or registers for giveaway <tag>.", module.giveawayCommand, 0)
+	if err != nil {
+		return nil, err
+	}
+	bot.AddSubEngine("giveaway", module.giveawayCmd)
+
+	// Giveaways saved before multiple channel support belong to the
+	// default channel.
+	var giveaways []Giveaway
+	module.db.All(&giveaways)
+	for _, g := range giveaways {
+		if g.Channel == "" {
+			g.Channel = bot.DefaultChannel()
+			module.db.Save(&g)
+		}
+	}
 
 	return module, nil
 }
 
 func (m *GiveawayModule) Start() error {
+	var giveaways []Giveaway
+	err := m.db.All(&giveaways)
+	if err != nil {
+		return err
+	}
+
+	// Winners drawn before a restart still get their remaining time to
+	// claim.  Those whose time ran out are expired right away.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.bot.Clock().Now()
+	for _, g := range giveaways {
+		w := g.PendingWinner()
+		if w == nil {
+			continue
+		}
+		m.scheduleClaimExpiry(g.ID, w.Drawn.Add(g.claimTimeout()).Sub(now))
+	}
 	return nil
 }
 
 func (m *GiveawayModule) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, t := range m.claimTimers {
+		t.Stop()
+		delete(m.claimTimers, id)
+	}
 	return nil
 }
 
@@ -51,21 +216,357 @@ func (m *GiveawayModule) GetRPCService() interface{} {
 	return m.service
 }
 
-func (m *GiveawayModule) giveawayDesc(cc *roll.CommandContext) error {
+func (m *GiveawayModule) channelGiveaways(channel string) ([]Giveaway, error) {
 	var giveaways []Giveaway
-	err := m.db.All(&giveaways)
+	err := m.db.Find("Channel", channel, &giveaways)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	return giveaways, err
+}
+
+func (m *GiveawayModule) findGiveaway(channel string, tag string) (*Giveaway, error) {
+	giveaways, err := m.channelGiveaways(channel)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range giveaways {
+		if g.Tag == tag {
+			return &g, nil
+		}
+	}
+	return nil, storm.ErrNotFound
+}
+
+// pointsModule returns the points module if it's loaded.
+func (m *GiveawayModule) pointsModule() *points.PointsModule {
+	mod, ok := m.bot.GetModule("points")
+	if !ok {
+		return nil
+	}
+	p, _ := mod.(*points.PointsModule)
+	return p
+}
+
+// multiplier returns what entry's tickets are multiplied by.
+func (g *Giveaway) multiplier(entry Entry) int {
+	if entry.Subscriber && g.SubscriberMultiplier > 1 {
+		return g.SubscriberMultiplier
+	}
+	return 1
+}
+
+// watched returns how long username has watched g's channel, or zero
+// without watch time bonuses or the points module.
+func (m *GiveawayModule) watched(g *Giveaway, username string) time.Duration {
+	if g.WatchTimeBonus.Duration <= 0 {
+		return 0
+	}
+	p := m.pointsModule()
+	if p == nil {
+		return 0
+	}
+	watched, err := p.WatchTime(g.Channel, username)
+	if err != nil {
+		log.Printf("Can't get watch time of %s: %v", username, err)
+	}
+	return watched
+}
+
+// baseTickets returns the tickets username has in g before the subscriber
+// multiplier and the cap.
+func (g *Giveaway) baseTickets(username string, watched time.Duration) int {
+	tickets := 1 + g.Entries[username].Tickets
+	if g.WatchTimeBonus.Duration > 0 {
+		tickets += int(watched / g.WatchTimeBonus.Duration)
+	}
+	return tickets
+}
+
+// tickets returns username's effective tickets in g after watching for
+// watched.
+func (g *Giveaway) tickets(username string, watched time.Duration) int {
+	tickets := g.baseTickets(username, watched) * g.multiplier(g.Entries[username])
+	if g.MaxTickets > 0 && tickets > g.MaxTickets {
+		tickets = g.MaxTickets
+	}
+	return tickets
+}
+
+// ticketsLeft returns how many more tickets username can buy before
+// reaching g's MaxTickets.  Tickets over the cap wouldn't count.
+func (g *Giveaway) ticketsLeft(username string, watched time.Duration) int {
+	return g.MaxTickets/g.multiplier(g.Entries[username]) - g.baseTickets(username, watched)
+}
+
+// pick returns the index of the weight that ticket, a number below the
+// sum of weights, falls in.
+func pick(weights []int, ticket int) int {
+	i := 0
+	for ticket >= weights[i] {
+		ticket -= weights[i]
+		i++
+	}
+	return i
+}
+
+// Odds returns the chances of each participant in giveaway id who hasn't
+// already won.
+func (m *GiveawayModule) Odds(id int) ([]Odds, error) {
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		return nil, err
+	}
+
+	var odds []Odds
+	total := 0
+	for _, p := range g.Participants {
+		if g.hasWon(p) {
+			continue
+		}
+		t := g.tickets(p, m.watched(&g, p))
+		total += t
+		odds = append(odds, Odds{Username: p, Tickets: t})
+	}
+	for i := range odds {
+		odds[i].Percent = float64(odds[i].Tickets) * 100 / float64(total)
+	}
+	return odds, nil
+}
+
+// BuyTickets spends username's points on extra tickets for channel's
+// giveaway tag and returns their total bought tickets.
+func (m *GiveawayModule) BuyTickets(channel string, tag string, username string, n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("you have to buy at least one ticket")
+	}
+	p := m.pointsModule()
+	if p == nil {
+		return 0, fmt.Errorf("points aren't enabled")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, err := m.findGiveaway(channel, tag)
+	if err != nil {
+		return 0, err
+	}
+	if g.TicketCost <= 0 {
+		return 0, fmt.Errorf("tickets can't be bought for the %s giveaway", g.Tag)
+	}
+	if !g.Accepting(m.bot.Clock().Now()) {
+		return 0, fmt.Errorf("the %s giveaway isn't taking entries", g.Tag)
+	}
+	entry, ok := g.Entries[username]
+	if !ok && !g.hasParticipant(username) {
+		return 0, fmt.Errorf("enter the %s giveaway first", g.Tag)
+	}
+	if g.MaxTickets > 0 {
+		// Only charge for tickets that count under the cap.
+		left := g.ticketsLeft(username, m.watched(g, username))
+		if left <= 0 {
+			return 0, fmt.Errorf("you already have the most tickets allowed")
+		}
+		if n > left {
+			return 0, fmt.Errorf("you can only buy %d more tickets", left)
+		}
+	}
+
+	_, err = p.Debit(channel, username, n*g.TicketCost)
+	if err == points.ErrInsufficientPoints {
+		return 0, fmt.Errorf("%d tickets cost %d points", n, n*g.TicketCost)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	entry.Tickets += n
+	if g.Entries == nil {
+		g.Entries = make(map[string]Entry)
+	}
+	g.Entries[username] = entry
+	err = m.db.Save(g)
+	if err != nil {
+		// Don't keep points for tickets that weren't saved.
+		p.Credit(channel, username, n*g.TicketCost)
+		return 0, err
+	}
+	return entry.Tickets, nil
+}
+
+// randomIndex returns a uniformly random index below n.
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// Draw picks a random winner for giveaway id from the participants who
+// haven't already won it.  If the winner doesn't claim within the
+// giveaway's claim timeout another is drawn.
+func (m *GiveawayModule) Draw(id int) (*Winner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.draw(id)
+}
+
+// draw does the work of Draw.  m.mu must be held.
+func (m *GiveawayModule) draw(id int) (*Winner, error) {
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		return nil, err
+	}
+	if w := g.PendingWinner(); w != nil {
+		return nil, fmt.Errorf("%s hasn't claimed the %s giveaway yet", w.Username, g.Tag)
+	}
+
+	var candidates []string
+	var tickets []int
+	total := 0
+	for _, p := range g.Participants {
+		if !g.hasWon(p) {
+			t := g.tickets(p, m.watched(&g, p))
+			candidates = append(candidates, p)
+			tickets = append(tickets, t)
+			total += t
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no one left to draw for the %s giveaway", g.Tag)
+	}
+
+	ticket, err := randomIndex(total)
+	if err != nil {
+		return nil, err
+	}
+	g.Winners = append(g.Winners, Winner{
+		Username: candidates[pick(tickets, ticket)],
+		Drawn:    m.bot.Clock().Now(),
+	})
+	err = m.db.Save(&g)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := g.claimTimeout()
+	m.scheduleClaimExpiry(id, timeout)
+
+	winner := g.Winners[len(g.Winners)-1]
+	m.bot.SayPriority(g.Channel,
+		fmt.Sprintf("@%s won the %s giveaway! Type %sgiveaway claim within %s to claim it.",
+			winner.Username, g.Desc, m.bot.CommandPrefix(g.Channel), timeout),
+		roll.PriorityHigh)
+	return &winner, nil
+}
+
+// scheduleClaimExpiry expires giveaway id's pending winner after d.  m.mu
+// must be held.
+func (m *GiveawayModule) scheduleClaimExpiry(id int, d time.Duration) {
+	if t, ok := m.claimTimers[id]; ok {
+		t.Stop()
+	}
+	m.claimTimers[id] = m.bot.Clock().AfterFunc(d, func() { m.claimExpired(id) })
+}
+
+func (m *GiveawayModule) claimExpired(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.claimTimers, id)
+
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		log.Printf("Can't load giveaway %d: %v", id, err)
+		return
+	}
+	w := g.PendingWinner()
+	if w == nil {
+		return
+	}
+	w.Expired = true
+	err = m.db.Save(&g)
+	if err != nil {
+		log.Printf("Can't save giveaway %d: %v", id, err)
+		return
+	}
+
+	m.bot.SayPriority(g.Channel,
+		fmt.Sprintf("%s didn't claim the %s giveaway in time.  Drawing again.", w.Username, g.Desc),
+		roll.PriorityHigh)
+	_, err = m.draw(id)
+	if err != nil {
+		m.bot.Say(g.Channel, err.Error())
+	}
+}
+
+// Claim marks username's pending win in channel as claimed and returns
+// the giveaway.
+func (m *GiveawayModule) Claim(channel string, username string) (*Giveaway, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	giveaways, err := m.channelGiveaways(channel)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range giveaways {
+		w := g.PendingWinner()
+		if w == nil || w.Username != username {
+			continue
+		}
+		w.Claimed = true
+		err = m.db.Save(&g)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := m.claimTimers[g.ID]; ok {
+			t.Stop()
+			delete(m.claimTimers, g.ID)
+		}
+		return &g, nil
+	}
+	return nil, storm.ErrNotFound
+}
+
+// SetClosed opens or closes entries to channel's giveaway tag.
+func (m *GiveawayModule) SetClosed(channel string, tag string, closed bool) (*Giveaway, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, err := m.findGiveaway(channel, tag)
+	if err != nil {
+		return nil, err
+	}
+	g.Closed = closed
+	return g, m.db.Save(g)
+}
+
+func (m *GiveawayModule) giveawayDesc(cc *roll.CommandContext) error {
+	giveaways, err := m.channelGiveaways(cc.Channel)
 	if err != nil {
 		return err
 	}
 
-	cc.IRC.Say(cc.Channel,
-		"To register for one of the giveaways type !giveaway <tag>.  The list of tags are:")
+	// The listing can be long so it yields to other messages.
+	cc.Bot.SayPriority(cc.Channel,
+		fmt.Sprintf("To register for one of the giveaways type %sgiveaway <tag>.  The list of tags are:",
+			cc.Prefix),
+		roll.PriorityLow)
 
+	now := m.bot.Clock().Now()
 	for _, g := range giveaways {
-		cc.IRC.Say(cc.Channel, fmt.Sprintf("  %s - %s", g.Tag, g.Desc))
+		if !g.Accepting(now) {
+			continue
+		}
+		cc.Bot.SayPriority(cc.Channel, fmt.Sprintf("  %s - %s", g.Tag, g.Desc), roll.PriorityLow)
 	}
 
-	cc.IRC.Say(cc.Channel, "More information at: https://roll.konkers.net/")
+	cc.Bot.SayPriority(cc.Channel, "More information at: https://roll.konkers.net/", roll.PriorityLow)
 
 	return nil
 }
@@ -74,60 +575,267 @@ func (m *GiveawayModule) giveawayCommand(cc *roll.CommandContext, args []string)
 	if len(args) == 0 {
 		return m.giveawayDesc(cc)
 	}
+	if _, ok := m.giveawayCmd.Lookup(args[:1]); ok {
+		return m.giveawayCmd.Exec(cc, cc.UserLevel, args)
+	}
 
-	follows, err := cc.API.GetChannelFollows(cc.Channel)
+	if !cc.Bot.IsFollower(cc.Channel, cc.User.UserID) {
+		cc.Say("Giveaway only open to followers.  Please follow and try again :)")
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	giveaway, err := m.findGiveaway(cc.Channel, args[0])
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no %s giveaway.  Type %sgiveaway for a list",
+			args[0], cc.Prefix))
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	isFollower := false
-	for _, f := range follows.Follows {
-		if strconv.Itoa(f.User.ID) == cc.User.UserID {
-			isFollower = true
-			break
-		}
+
+	if !giveaway.Accepting(m.bot.Clock().Now()) {
+		cc.Say(fmt.Sprintf("%s, the %s giveaway isn't taking entries.",
+			cc.User.DisplayName, giveaway.Tag))
+		return nil
 	}
 
-	if !isFollower {
-		cc.IRC.Say(cc.Channel, "Giveaway only open to followers.  Please follow and try again :)")
+	if giveaway.hasParticipant(cc.User.Username) {
+		cc.Say(fmt.Sprintf("%s, you're already registered.",
+			cc.User.DisplayName))
 		return nil
 	}
 
-	var giveaways []Giveaway
-	err = m.db.All(&giveaways)
+	giveaway.Participants = append(giveaway.Participants, cc.User.Username)
+	if giveaway.Entries == nil {
+		giveaway.Entries = make(map[string]Entry)
+	}
+	giveaway.Entries[cc.User.Username] = Entry{Subscriber: roll.IsSubscriber(cc.User)}
+	err = m.db.Save(giveaway)
 	if err != nil {
 		return err
 	}
 
-	var giveaway *Giveaway
-	for _, g := range giveaways {
-		if g.Tag == args[0] {
-			giveaway = &g
-			break
-		}
+	cc.Say(fmt.Sprintf("%s, you're now registered for the %s giveaway.",
+		cc.User.Username, giveaway.Desc))
+	return nil
+}
+
+func (m *GiveawayModule) giveawayOpenCommand(cc *roll.CommandContext, args []string) error {
+	return m.setClosedCommand(cc, args, false)
+}
+
+func (m *GiveawayModule) giveawayCloseCommand(cc *roll.CommandContext, args []string) error {
+	return m.setClosedCommand(cc, args, true)
+}
+
+func (m *GiveawayModule) setClosedCommand(cc *roll.CommandContext, args []string, closed bool) error {
+	if len(args) != 1 {
+		cc.Say("Please specify a giveaway tag.")
+		return nil
 	}
 
-	if giveaway == nil {
-		cc.IRC.Say(cc.Channel,
-			fmt.Sprintf("There's no %s giveaway.  Type !giveaway for a list", args[0]))
+	g, err := m.SetClosed(cc.Channel, args[0], closed)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no %s giveaway.", args[0]))
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	log.Printf("%#v", giveaway)
-	for _, p := range giveaway.Participants {
-		if p == cc.User.Username {
-			cc.IRC.Say(cc.Channel, fmt.Sprintf("%s, you're already registered.",
-				cc.User.DisplayName))
-			return nil
-		}
+	if closed {
+		cc.Say(fmt.Sprintf("Entries for the %s giveaway are closed.", g.Desc))
+	} else {
+		cc.Say(fmt.Sprintf("Entries for the %s giveaway are open!  Type %sgiveaway %s to enter.",
+			g.Desc, cc.Prefix, g.Tag))
 	}
+	return nil
+}
 
-	giveaway.Participants = append(giveaway.Participants, cc.User.Username)
-	err = m.db.Save(giveaway)
+func (m *GiveawayModule) giveawayDrawCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) != 1 {
+		cc.Say("Please specify a giveaway tag.")
+		return nil
+	}
+
+	g, err := m.findGiveaway(cc.Channel, args[0])
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no %s giveaway.", args[0]))
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	cc.IRC.Say(cc.Channel, fmt.Sprintf("%s, you're now registered for the %s giveaway.",
-		cc.User.Username, giveaway.Desc))
+	_, err = m.Draw(g.ID)
+	if err != nil {
+		cc.Say(err.Error())
+	}
+	return nil
+}
+
+func (m *GiveawayModule) giveawayClaimCommand(cc *roll.CommandContext, args []string) error {
+	g, err := m.Claim(cc.Channel, cc.User.Username)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("%s, you don't have a prize to claim.", cc.User.DisplayName))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("Congratulations %s, you've claimed the %s giveaway!",
+		cc.User.DisplayName, g.Desc))
 	return nil
 }
+
+func (m *GiveawayModule) giveawayBuyCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) != 2 {
+		cc.Say(fmt.Sprintf("Usage: %sgiveaway buy <tag> <n>", cc.Prefix))
+		return nil
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s is not a number.", args[1]))
+		return nil
+	}
+
+	tickets, err := m.BuyTickets(cc.Channel, args[0], cc.User.Username, n)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no %s giveaway.", args[0]))
+		return nil
+	}
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s, %v.", cc.User.DisplayName, err))
+		return nil
+	}
+	cc.Say(fmt.Sprintf("%s, you now have %d extra tickets for the %s giveaway.",
+		cc.User.DisplayName, tickets, args[0]))
+	return nil
+}
+
+// SaveGiveaway creates or updates g's settings.  An existing giveaway keeps
+// its participants, entries and winners, which only change through chat
+// and the participant calls.
+func (m *GiveawayModule) SaveGiveaway(g *Giveaway) error {
+	if g.Tag == "" {
+		return fmt.Errorf("a giveaway needs a tag")
+	}
+	// Entering is "giveaway <tag>", so a tag can't shadow a sub-command.
+	if _, ok := m.giveawayCmd.Lookup([]string{strings.ToLower(g.Tag)}); ok {
+		return fmt.Errorf("%s is a giveaway command and can't be used as a tag", g.Tag)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if g.ID != 0 {
+		var cur Giveaway
+		err := m.db.One("ID", g.ID, &cur)
+		if err != nil {
+			return err
+		}
+		g.Participants = cur.Participants
+		g.Entries = cur.Entries
+		g.Winners = cur.Winners
+	}
+	return m.db.Save(g)
+}
+
+// DeleteGiveaway removes giveaway id and cancels any pending claim.
+func (m *GiveawayModule) DeleteGiveaway(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		return err
+	}
+	if t, ok := m.claimTimers[id]; ok {
+		t.Stop()
+		delete(m.claimTimers, id)
+	}
+	return m.db.DeleteStruct(&g)
+}
+
+// RemoveParticipant removes username's entry from giveaway id.
+func (m *GiveawayModule) RemoveParticipant(id int, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		return err
+	}
+
+	username = strings.ToLower(username)
+	if !g.hasParticipant(username) {
+		return fmt.Errorf("%s isn't in the %s giveaway", username, g.Tag)
+	}
+	var participants []string
+	for _, p := range g.Participants {
+		if p != username {
+			participants = append(participants, p)
+		}
+	}
+	g.Participants = participants
+	delete(g.Entries, username)
+	return m.db.Save(&g)
+}
+
+// ExportEntry is a row of a participant export.
+type ExportEntry struct {
+	Username   string `json:"username"`
+	Subscriber bool   `json:"subscriber"`
+	Bought     int    `json:"bought"`
+	Tickets    int    `json:"tickets"`
+	Won        bool   `json:"won"`
+}
+
+// Export returns the participants of giveaway id as "csv" or "json".
+func (m *GiveawayModule) Export(id int, format string) ([]byte, error) {
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ExportEntry{}
+	for _, p := range g.Participants {
+		entry := g.Entries[p]
+		entries = append(entries, ExportEntry{
+			Username:   p,
+			Subscriber: entry.Subscriber,
+			Bought:     entry.Tickets,
+			Tickets:    g.tickets(p, m.watched(&g, p)),
+			Won:        g.hasWon(p),
+		})
+	}
+
+	switch format {
+	case "json":
+		return json.MarshalIndent(entries, "", "  ")
+	case "csv", "":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"username", "subscriber", "bought", "tickets", "won"})
+		for _, e := range entries {
+			w.Write([]string{
+				e.Username,
+				strconv.FormatBool(e.Subscriber),
+				strconv.Itoa(e.Bought),
+				strconv.Itoa(e.Tickets),
+				strconv.FormatBool(e.Won),
+			})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	default:
+		return nil, fmt.Errorf("unknown export format %s", format)
+	}
+}
@@ -0,0 +1,314 @@
+package giveaway
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/konkers/roll"
+	"github.com/konkers/roll/clocktest"
+	"github.com/konkers/roll/modules/points"
+	"github.com/konkers/roll/rolltest"
+)
+
+const channel = rolltest.Channel
+
+func newTestModule(t *testing.T) (*GiveawayModule, *clocktest.Clock) {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	bot := rolltest.NewBot(t, clock)
+	return rolltest.AddModule(t, bot, "giveaway").(*GiveawayModule), clock
+}
+
+func TestSaveGiveawayTags(t *testing.T) {
+	m, _ := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	for _, test := range []struct {
+		tag string
+		ok  bool
+	}{
+		{"game", true},
+		{"", false},
+		{"open", false},
+		{"Close", false},
+		{"draw", false},
+		{"claim", false},
+		{"buy", false},
+		{"buying", true},
+	} {
+		err := m.SaveGiveaway(&Giveaway{Channel: channel, Tag: test.tag})
+		if (err == nil) != test.ok {
+			t.Errorf("SaveGiveaway() with tag %q returned error %v", test.tag, err)
+		}
+	}
+}
+
+func TestGiveawayAccepting(t *testing.T) {
+	start := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	for _, test := range []struct {
+		g         Giveaway
+		t         time.Time
+		accepting bool
+	}{
+		{Giveaway{}, start, true},
+		{Giveaway{Closed: true}, start, false},
+		{Giveaway{StartTime: start}, start.Add(-time.Second), false},
+		{Giveaway{StartTime: start}, start, true},
+		{Giveaway{EndTime: end}, end.Add(-time.Second), true},
+		{Giveaway{EndTime: end}, end, false},
+		{Giveaway{StartTime: start, EndTime: end}, start.Add(30 * time.Minute), true},
+		{Giveaway{StartTime: start, EndTime: end, Closed: true}, start.Add(30 * time.Minute), false},
+	} {
+		if accepting := test.g.Accepting(test.t); accepting != test.accepting {
+			t.Errorf("Accepting(%v) of %v-%v closed %v returned %v", test.t,
+				test.g.StartTime, test.g.EndTime, test.g.Closed, accepting)
+		}
+	}
+}
+
+// saveTestGiveaway saves a giveaway for participants with a one minute
+// claim timeout.
+func saveTestGiveaway(t *testing.T, m *GiveawayModule, participants ...string) *Giveaway {
+	g := &Giveaway{
+		Channel:      channel,
+		Tag:          "game",
+		Desc:         "a game",
+		Participants: participants,
+		ClaimTimeout: roll.Duration{Duration: time.Minute},
+	}
+	err := m.SaveGiveaway(g)
+	if err != nil {
+		t.Fatalf("SaveGiveaway() returned error: %v", err)
+	}
+	return g
+}
+
+func loadGiveaway(t *testing.T, m *GiveawayModule, id int) *Giveaway {
+	var g Giveaway
+	err := m.db.One("ID", id, &g)
+	if err != nil {
+		t.Fatalf("Can't load giveaway %d: %v", id, err)
+	}
+	return &g
+}
+
+func TestGiveawayClaimTimeout(t *testing.T) {
+	m, clock := newTestModule(t)
+	defer rolltest.Close(m.bot)
+	g := saveTestGiveaway(t, m, "alice", "bob", "carol")
+
+	first, err := m.Draw(g.ID)
+	if err != nil {
+		t.Fatalf("Draw() returned error: %v", err)
+	}
+	if _, err := m.Draw(g.ID); err == nil {
+		t.Errorf("Draw() with a winner pending didn't return an error")
+	}
+
+	clock.Advance(59 * time.Second)
+	if w := loadGiveaway(t, m, g.ID).PendingWinner(); w == nil || w.Username != first.Username {
+		t.Fatalf("Pending winner before the timeout is %v instead of %s", w, first.Username)
+	}
+
+	// Running out of time expires the winner and draws someone else.
+	clock.Advance(time.Second)
+	g = loadGiveaway(t, m, g.ID)
+	if len(g.Winners) != 2 || !g.Winners[0].Expired {
+		t.Fatalf("Winners after the timeout are %v", g.Winners)
+	}
+	second := g.Winners[1]
+	if second.Username == first.Username || !second.Drawn.Equal(clock.Now()) {
+		t.Errorf("Redrew %v after %s expired", second, first.Username)
+	}
+
+	if _, err := m.Claim(rolltest.OtherChannel, second.Username); err == nil {
+		t.Errorf("Claim() in another channel succeeded")
+	}
+	if _, err := m.Claim(channel, first.Username); err == nil {
+		t.Errorf("Claim() by an expired winner succeeded")
+	}
+	if _, err := m.Claim(channel, second.Username); err != nil {
+		t.Fatalf("Claim() returned error: %v", err)
+	}
+
+	// A claimed win doesn't expire.
+	clock.Advance(time.Hour)
+	g = loadGiveaway(t, m, g.ID)
+	if len(g.Winners) != 2 || !g.Winners[1].Claimed || g.Winners[1].Expired {
+		t.Fatalf("Winners after claiming are %v", g.Winners)
+	}
+
+	// Past winners, claimed or not, aren't drawn again.
+	third, err := m.Draw(g.ID)
+	if err != nil {
+		t.Fatalf("Draw() returned error: %v", err)
+	}
+	if third.Username == first.Username || third.Username == second.Username {
+		t.Errorf("Drew past winner %s", third.Username)
+	}
+	m.Claim(channel, third.Username)
+	if _, err := m.Draw(g.ID); err == nil {
+		t.Errorf("Draw() with everyone drawn didn't return an error")
+	}
+}
+
+func TestGiveawayStartReschedulesClaims(t *testing.T) {
+	m, clock := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	// Winners pending from before a restart: one with 30s left and one
+	// whose time ran out while the bot was down.
+	pending := saveTestGiveaway(t, m, "alice", "bob")
+	pending.Winners = []Winner{{Username: "alice", Drawn: clock.Now().Add(-30 * time.Second)}}
+	overdue := saveTestGiveaway(t, m, "carol", "dave")
+	overdue.Tag = "other"
+	overdue.Winners = []Winner{{Username: "carol", Drawn: clock.Now().Add(-2 * time.Minute)}}
+	for _, g := range []*Giveaway{pending, overdue} {
+		if err := m.db.Save(g); err != nil {
+			t.Fatalf("Can't save giveaway: %v", err)
+		}
+	}
+
+	err := m.Start()
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	defer m.Stop()
+
+	clock.Advance(0)
+	g := loadGiveaway(t, m, overdue.ID)
+	if len(g.Winners) != 2 || !g.Winners[0].Expired || g.Winners[1].Username != "dave" {
+		t.Errorf("Overdue winners after Start() are %v", g.Winners)
+	}
+	g = loadGiveaway(t, m, pending.ID)
+	if len(g.Winners) != 1 || g.Winners[0].Expired {
+		t.Errorf("Pending winners after Start() are %v", g.Winners)
+	}
+
+	clock.Advance(30 * time.Second)
+	g = loadGiveaway(t, m, pending.ID)
+	if len(g.Winners) != 2 || !g.Winners[0].Expired || g.Winners[1].Username != "bob" {
+		t.Errorf("Pending winners after their timeout are %v", g.Winners)
+	}
+}
+
+func TestGiveawayTickets(t *testing.T) {
+	hour := roll.Duration{Duration: time.Hour}
+
+	for _, test := range []struct {
+		what    string
+		g       Giveaway
+		entry   Entry
+		watched time.Duration
+		tickets int
+		left    int
+	}{
+		{"No weighting", Giveaway{}, Entry{Subscriber: true}, 5 * time.Hour, 1, 0},
+		{"Subscriber", Giveaway{SubscriberMultiplier: 3}, Entry{Subscriber: true}, 0, 3, 0},
+		{"Non-subscriber", Giveaway{SubscriberMultiplier: 3}, Entry{}, 0, 1, 0},
+		{"Bought", Giveaway{}, Entry{Tickets: 4}, 0, 5, 0},
+		{"Watch time", Giveaway{WatchTimeBonus: hour}, Entry{}, 150 * time.Minute, 3, 0},
+		{"Everything", Giveaway{SubscriberMultiplier: 2, WatchTimeBonus: hour},
+			Entry{Subscriber: true, Tickets: 2}, time.Hour, 8, 0},
+		{"Under the cap", Giveaway{MaxTickets: 5}, Entry{Tickets: 3}, 0, 4, 1},
+		{"At the cap", Giveaway{MaxTickets: 5}, Entry{Tickets: 4}, 0, 5, 0},
+		{"Over the cap", Giveaway{MaxTickets: 5, WatchTimeBonus: hour},
+			Entry{Tickets: 4}, 3 * time.Hour, 5, -3},
+		// Only whole multiples of the multiplier fit under the cap.
+		{"Subscriber under the cap", Giveaway{MaxTickets: 7, SubscriberMultiplier: 2},
+			Entry{Subscriber: true, Tickets: 1}, 0, 4, 1},
+		{"Subscriber at the cap", Giveaway{MaxTickets: 7, SubscriberMultiplier: 2},
+			Entry{Subscriber: true, Tickets: 2}, 0, 6, 0},
+	} {
+		test.g.Entries = map[string]Entry{"alice": test.entry}
+		if tickets := test.g.tickets("alice", test.watched); tickets != test.tickets {
+			t.Errorf("%s: tickets() returned %d instead of %d", test.what, tickets, test.tickets)
+		}
+		if test.g.MaxTickets == 0 {
+			continue
+		}
+		if left := test.g.ticketsLeft("alice", test.watched); left != test.left {
+			t.Errorf("%s: ticketsLeft() returned %d instead of %d", test.what, left, test.left)
+		}
+	}
+}
+
+func TestGiveawayPick(t *testing.T) {
+	weights := []int{2, 1, 3}
+	for ticket, want := range []int{0, 0, 1, 2, 2, 2} {
+		if i := pick(weights, ticket); i != want {
+			t.Errorf("pick(%v, %d) returned %d instead of %d", weights, ticket, i, want)
+		}
+	}
+}
+
+func TestGiveawayOdds(t *testing.T) {
+	m, _ := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	g := saveTestGiveaway(t, m, "alice", "bob", "carol", "dave")
+	g.SubscriberMultiplier = 2
+	g.Entries = map[string]Entry{
+		"alice": {Subscriber: true},
+		"bob":   {Tickets: 2},
+	}
+	g.Winners = []Winner{{Username: "dave", Claimed: true}}
+	err := m.db.Save(g)
+	if err != nil {
+		t.Fatalf("Can't save giveaway: %v", err)
+	}
+
+	odds, err := m.Odds(g.ID)
+	if err != nil {
+		t.Fatalf("Odds() returned error: %v", err)
+	}
+	// Past winners have no chance.
+	want := []Odds{
+		{"alice", 2, 100.0 * 2 / 6},
+		{"bob", 3, 100.0 * 3 / 6},
+		{"carol", 1, 100.0 * 1 / 6},
+	}
+	if !reflect.DeepEqual(odds, want) {
+		t.Errorf("Odds() returned %v instead of %v", odds, want)
+	}
+	total := 0.0
+	for _, o := range odds {
+		total += o.Percent
+	}
+	if math.Abs(total-100) > 1e-9 {
+		t.Errorf("Odds() add up to %v%%", total)
+	}
+}
+
+func TestGiveawayBuyTicketsCap(t *testing.T) {
+	m, _ := newTestModule(t)
+	defer rolltest.Close(m.bot)
+	p := rolltest.AddModule(t, m.bot, "points").(*points.PointsModule)
+
+	g := saveTestGiveaway(t, m, "alice")
+	g.TicketCost = 10
+	g.MaxTickets = 4
+	err := m.db.Save(g)
+	if err != nil {
+		t.Fatalf("Can't save giveaway: %v", err)
+	}
+	p.Credit(channel, "alice", 100)
+
+	if _, err := m.BuyTickets(channel, "game", "alice", 4); err == nil {
+		t.Errorf("BuyTickets() over the cap succeeded")
+	}
+	bought, err := m.BuyTickets(channel, "game", "alice", 3)
+	if err != nil || bought != 3 {
+		t.Errorf("BuyTickets() up to the cap returned %d, %v instead of 3, nil", bought, err)
+	}
+	if _, err := m.BuyTickets(channel, "game", "alice", 1); err == nil {
+		t.Errorf("BuyTickets() at the cap succeeded")
+	}
+	// Only the tickets bought were paid for.
+	if balance, _ := p.Balance(channel, "alice"); balance != 70 {
+		t.Errorf("alice has %d points left instead of 70", balance)
+	}
+}
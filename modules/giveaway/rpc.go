@@ -3,18 +3,47 @@ package giveaway
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/asdine/storm"
 )
 
 type GiveawayService struct {
 	module *GiveawayModule
 }
 
+type GiveawayList struct {
+	Giveaways []Giveaway `json:"giveaways"`
+}
+
+type ParticipantArgs struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type ExportArgs struct {
+	ID int `json:"id"`
+	// "csv" (the default) or "json".
+	Format string `json:"format"`
+}
+
+type ExportReply struct {
+	ContentType string `json:"content_type"`
+	Data        string `json:"data"`
+}
+
 func NewGiveawayService(module *GiveawayModule) *GiveawayService {
 	return &GiveawayService{
 		module: module,
 	}
 }
 
+func (s *GiveawayService) channel(channel string) string {
+	if channel == "" {
+		return s.module.bot.DefaultChannel()
+	}
+	return channel
+}
+
 func (s *GiveawayService) New(r *http.Request, g *Giveaway, id *int) error {
 	g.ID = 0
 	return s.Update(r, g, id)
@@ -24,7 +53,10 @@ func (s *GiveawayService) Update(r *http.Request, g *Giveaway, id *int) error {
 	if !s.module.bot.IsAdminRequest(r) {
 		return fmt.Errorf("access denied")
 	}
-	err := s.module.db.Save(g)
+	if g.Channel == "" {
+		g.Channel = s.module.bot.DefaultChannel()
+	}
+	err := s.module.SaveGiveaway(g)
 	if err != nil {
 		*id = -1
 		return err
@@ -35,6 +67,84 @@ func (s *GiveawayService) Update(r *http.Request, g *Giveaway, id *int) error {
 }
 
 func (s *GiveawayService) Get(r *http.Request, id *int, g *Giveaway) error {
-	err := s.module.db.One("ID", *id, g)
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	return s.module.db.One("ID", *id, g)
+}
+
+func (s *GiveawayService) Del(r *http.Request, id *int, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.module.DeleteGiveaway(*id)
+	if err != nil {
+		return err
+	}
+	*ret = *id
+	return nil
+}
+
+func (s *GiveawayService) All(r *http.Request, channel *string, list *GiveawayList) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.module.db.Find("Channel", s.channel(*channel), &list.Giveaways)
+	if err == storm.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *GiveawayService) RemoveParticipant(r *http.Request, args *ParticipantArgs, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.module.RemoveParticipant(args.ID, args.Username)
+	if err != nil {
+		return err
+	}
+	*ret = args.ID
+	return nil
+}
+
+func (s *GiveawayService) Export(r *http.Request, args *ExportArgs, reply *ExportReply) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	data, err := s.module.Export(args.ID, args.Format)
+	if err != nil {
+		return err
+	}
+	reply.ContentType = "text/csv"
+	if args.Format == "json" {
+		reply.ContentType = "application/json"
+	}
+	reply.Data = string(data)
+	return nil
+}
+
+func (s *GiveawayService) Draw(r *http.Request, id *int, w *Winner) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	winner, err := s.module.Draw(*id)
+	if err != nil {
+		return err
+	}
+	*w = *winner
+	return nil
+}
+
+type OddsList struct {
+	Odds []Odds `json:"odds"`
+}
+
+func (s *GiveawayService) Odds(r *http.Request, id *int, list *OddsList) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	var err error
+	list.Odds, err = s.module.Odds(*id)
 	return err
 }
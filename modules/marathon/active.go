@@ -0,0 +1,309 @@
+package marathon
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/konkers/roll"
+)
+
+// Active marathon ids are kept in this bucket keyed by channel.
+const activeBucket = "active"
+
+// ActiveMarathon returns the id of channel's active marathon.
+func (m *MarathonModule) ActiveMarathon(channel string) int {
+	id := CurrentMarathon
+	err := m.db.Get(activeBucket, channel, &id)
+	if err != nil && err != storm.ErrNotFound {
+		log.Printf("Can't get active marathon of %s: %v", channel, err)
+	}
+	return id
+}
+
+// SetActive makes marathon id the active one in channel.  The marathon
+// must belong to channel.
+func (m *MarathonModule) SetActive(channel string, id int) error {
+	var marathon Marathon
+	err := m.db.One("ID", id, &marathon)
+	if err != nil {
+		return err
+	}
+	if marathon.Channel != channel {
+		return fmt.Errorf("marathon %d belongs to %s", id, marathon.Channel)
+	}
+	return m.db.Set(activeBucket, channel, id)
+}
+
+// migrateChannels gives marathons saved before they belonged to a channel
+// the channel they're active in, or the default channel.
+func (m *MarathonModule) migrateChannels() {
+	marathons, err := m.Marathons()
+	if err != nil {
+		log.Printf("Can't get marathons: %v", err)
+		return
+	}
+	for _, marathon := range marathons {
+		if marathon.Channel != "" {
+			continue
+		}
+		marathon.Channel = m.bot.DefaultChannel()
+		for _, channel := range m.bot.Channels() {
+			var active int
+			if m.db.Get(activeBucket, channel, &active) == nil && active == marathon.ID {
+				marathon.Channel = channel
+				break
+			}
+		}
+		err = m.db.Save(&marathon)
+		if err != nil {
+			log.Printf("Can't migrate marathon %d: %v", marathon.ID, err)
+		}
+	}
+}
+
+// isActive reports whether marathon id is active in any channel.
+func (m *MarathonModule) isActive(id int) bool {
+	for _, channel := range m.bot.Channels() {
+		if m.ActiveMarathon(channel) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Marathons returns all marathons ordered by id.
+func (m *MarathonModule) Marathons() ([]Marathon, error) {
+	var marathons []Marathon
+	err := m.db.All(&marathons)
+	sort.Slice(marathons, func(i, j int) bool { return marathons[i].ID < marathons[j].ID })
+	return marathons, err
+}
+
+// channelMarathons returns channel's marathons ordered by id.
+func (m *MarathonModule) channelMarathons(channel string) ([]Marathon, error) {
+	var marathons []Marathon
+	err := m.db.Find("Channel", channel, &marathons)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	sort.Slice(marathons, func(i, j int) bool { return marathons[i].ID < marathons[j].ID })
+	return marathons, err
+}
+
+// Archived returns the marathons that aren't active in any channel.
+func (m *MarathonModule) Archived() ([]Marathon, error) {
+	marathons, err := m.Marathons()
+	if err != nil {
+		return nil, err
+	}
+	var archived []Marathon
+	for _, marathon := range marathons {
+		if !m.isActive(marathon.ID) {
+			archived = append(archived, marathon)
+		}
+	}
+	return archived, nil
+}
+
+// DeleteMarathon removes marathon id and its history.
+func (m *MarathonModule) DeleteMarathon(id int) error {
+	var marathon Marathon
+	err := m.db.One("ID", id, &marathon)
+	if err != nil {
+		return err
+	}
+
+	events, err := m.History(id)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		err = m.history().DeleteStruct(&e)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, channel := range m.bot.Channels() {
+		var active int
+		if m.db.Get(activeBucket, channel, &active) == nil && active == id {
+			m.db.Delete(activeBucket, channel)
+		}
+	}
+	return m.db.DeleteStruct(&marathon)
+}
+
+// requestMarathon returns the marathon a web request asks for with the
+// id parameter or, failing that, the active one of the channel parameter.
+func (m *MarathonModule) requestMarathon(req *http.Request) (*Marathon, error) {
+	if idStr := req.FormValue("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, err
+		}
+		var marathon Marathon
+		err = m.db.One("ID", id, &marathon)
+		if err != nil {
+			return nil, err
+		}
+		return &marathon, nil
+	}
+
+	channel := req.FormValue("channel")
+	if channel == "" {
+		channel = m.bot.DefaultChannel()
+	}
+	return m.loadMarathon(channel)
+}
+
+func marathonName(marathon *Marathon) string {
+	if marathon.Name == nil {
+		return fmt.Sprintf("Marathon %d", marathon.ID)
+	}
+	return *marathon.Name
+}
+
+func (m *MarathonModule) marathonListCommand(cc *roll.CommandContext, args []string) error {
+	marathons, err := m.channelMarathons(cc.Channel)
+	if err != nil {
+		return err
+	}
+	if len(marathons) == 0 {
+		cc.Say("There are no marathons.")
+		return nil
+	}
+
+	active := m.ActiveMarathon(cc.Channel)
+	msg := "Marathons:"
+	for _, marathon := range marathons {
+		msg += fmt.Sprintf(" %d) %s", marathon.ID, marathonName(&marathon))
+		if marathon.ID == active {
+			msg += " (active)"
+		}
+	}
+	cc.Say(msg)
+	return nil
+}
+
+func (m *MarathonModule) marathonUseCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) != 1 {
+		cc.Say(fmt.Sprintf("Usage: %smarathon use <id>", cc.Prefix))
+		return nil
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s is not a marathon id.", args[0]))
+		return nil
+	}
+
+	var marathon Marathon
+	err = m.db.One("ID", id, &marathon)
+	if err == storm.ErrNotFound || (err == nil && marathon.Channel != cc.Channel) {
+		cc.Say(fmt.Sprintf("There's no marathon %d.", id))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = m.SetActive(cc.Channel, id)
+	if err != nil {
+		return err
+	}
+
+	cc.Say(fmt.Sprintf("Now running %s.", marathonName(&marathon)))
+	return nil
+}
+
+func (m *MarathonModule) archiveHandler(w http.ResponseWriter, req *http.Request) {
+	marathons, err := m.Archived()
+	if err != nil {
+		log.Printf("Can't get marathons: %v", err)
+		http.Error(w, "Can't get marathons", http.StatusInternalServerError)
+		return
+	}
+
+	type archived struct {
+		Marathon Marathon
+		Start    time.Time
+		Done     int
+	}
+	var data []archived
+	for _, marathon := range marathons {
+		a := archived{
+			Marathon: marathon,
+			Done:     marathon.GamesDone(),
+		}
+		if len(marathon.Games) > 0 && marathon.Games[0].StartedTime != nil {
+			a.Start = *marathon.Games[0].StartedTime
+		} else if marathon.StartTime != nil {
+			a.Start = *marathon.StartTime
+		}
+		data = append(data, a)
+	}
+
+	err = m.bot.ExecuteTemplate(w, "marathon_archive.html", data)
+	if err != nil {
+		log.Printf("Can't render marathon archive: %v", err)
+	}
+}
+
+type MarathonList struct {
+	Marathons []Marathon `json:"marathons"`
+}
+
+type ActiveArgs struct {
+	Channel string `json:"channel"`
+	ID      int    `json:"id"`
+}
+
+func (s *MarathonService) All(r *http.Request, channel *string, list *MarathonList) error {
+	c := *channel
+	if c == "" {
+		c = s.module.bot.DefaultChannel()
+	}
+	var err error
+	list.Marathons, err = s.module.channelMarathons(c)
+	return err
+}
+
+func (s *MarathonService) Del(r *http.Request, id *int, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.module.DeleteMarathon(*id)
+	if err != nil {
+		return err
+	}
+	*ret = *id
+	return nil
+}
+
+func (s *MarathonService) SetActive(r *http.Request, args *ActiveArgs, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	channel := args.Channel
+	if channel == "" {
+		channel = s.module.bot.DefaultChannel()
+	}
+	err := s.module.SetActive(channel, args.ID)
+	if err != nil {
+		return err
+	}
+	*ret = args.ID
+	return nil
+}
+
+func (s *MarathonService) Active(r *http.Request, channel *string, id *int) error {
+	c := *channel
+	if c == "" {
+		c = s.module.bot.DefaultChannel()
+	}
+	*id = s.module.ActiveMarathon(c)
+	return nil
+}
@@ -0,0 +1,377 @@
+package marathon
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/konkers/roll"
+)
+
+const historyBucket = "history"
+
+// Split is a time recorded during a game.
+type Split struct {
+	Name string `json:"name"`
+	// Game time when the split was recorded.
+	Time roll.Duration `json:"time"`
+}
+
+// RunEvent is an entry in a marathon's append-only history.
+type RunEvent struct {
+	ID         int       `json:"id" storm:"id,increment"`
+	MarathonID int       `json:"marathon_id" storm:"index"`
+	Time       time.Time `json:"time"`
+	// The command that caused the event, or "finish" when a game ends.
+	Action string `json:"action"`
+	// Number of the game acted on, counting from 1.  Zero if none.
+	Game     int    `json:"game"`
+	GameName string `json:"game_name"`
+	By       string `json:"by"`
+
+	// Set on finish events.
+	Elapsed roll.Duration `json:"elapsed"`
+	Splits  []Split       `json:"splits"`
+}
+
+// GameResult is a finished game's timing.
+type GameResult struct {
+	Game     int           `json:"game"`
+	Name     string        `json:"name"`
+	Estimate roll.Duration `json:"estimate"`
+	Elapsed  roll.Duration `json:"elapsed"`
+	Splits   []Split       `json:"splits"`
+	Finished time.Time     `json:"finished"`
+}
+
+// Run is the results between marathon resets.
+type Run struct {
+	Results []GameResult  `json:"results"`
+	Resets  int           `json:"resets"`
+	Total   roll.Duration `json:"total"`
+}
+
+// Pause stops g's timer at now.
+func (g *MarathonGame) Pause(now time.Time) error {
+	if g.status() != GameStatusRunning {
+		return fmt.Errorf("Game isn't running.")
+	}
+	if g.Paused != nil {
+		return fmt.Errorf("Already paused.")
+	}
+	g.Paused = &now
+	return nil
+}
+
+// Resume restarts g's timer at now.
+func (g *MarathonGame) Resume(now time.Time) error {
+	if g.Paused == nil {
+		return fmt.Errorf("Not paused.")
+	}
+	paused := now.Sub(*g.Paused)
+	if g.PausedTotal == nil {
+		g.PausedTotal = &roll.Duration{}
+	}
+	g.PausedTotal.Duration += paused
+	g.Paused = nil
+	return nil
+}
+
+// Split records the running game's time at now under name.
+func (m *Marathon) Split(name string, now time.Time) (*Split, error) {
+	game := m.CurrentGame()
+	if game == nil {
+		return nil, fmt.Errorf("Marathon is not running.")
+	}
+	if name == "" {
+		name = strconv.Itoa(len(game.Splits) + 1)
+	}
+	game.Splits = append(game.Splits, Split{
+		Name: name,
+		Time: roll.Duration{Duration: game.Elapsed(now)},
+	})
+	return &game.Splits[len(game.Splits)-1], nil
+}
+
+func (m *Marathon) statuses() []MarathonGameStatus {
+	var statuses []MarathonGameStatus
+	for _, game := range m.Games {
+		statuses = append(statuses, game.status())
+	}
+	return statuses
+}
+
+func (m *MarathonModule) history() storm.Node {
+	return m.db.From(historyBucket)
+}
+
+// record appends action to marathon's history, along with a finish event
+// for each game that finished since statuses before were taken.
+func (m *MarathonModule) record(marathon *Marathon, before []MarathonGameStatus,
+	action string, by string) {
+	now := m.bot.Clock().Now()
+	events := []*RunEvent{{
+		MarathonID: marathon.ID,
+		Time:       now,
+		Action:     action,
+		By:         by,
+	}}
+	if i := marathon.CurrentGameIndex(); i >= 0 {
+		events[0].Game = i + 1
+		events[0].GameName = *marathon.Games[i].Name
+	}
+
+	for i, game := range marathon.Games {
+		if game.status() != GameStatusFinished ||
+			(i < len(before) && before[i] == GameStatusFinished) {
+			continue
+		}
+		events = append(events, &RunEvent{
+			MarathonID: marathon.ID,
+			Time:       now,
+			Action:     "finish",
+			Game:       i + 1,
+			GameName:   *game.Name,
+			By:         by,
+			Elapsed:    roll.Duration{Duration: game.Elapsed(now)},
+			Splits:     game.Splits,
+		})
+	}
+
+	for _, e := range events {
+		err := m.history().Save(e)
+		if err != nil {
+			log.Printf("Can't record marathon %s: %v", e.Action, err)
+		}
+	}
+}
+
+// History returns the events of marathon id in order.
+func (m *MarathonModule) History(id int) ([]RunEvent, error) {
+	var events []RunEvent
+	err := m.history().Find("MarathonID", id, &events)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, err
+}
+
+// Runs splits the history of marathon into runs separated by marathon
+// resets.  Each game's latest finish in a run is its result.
+func (m *MarathonModule) Runs(marathon *Marathon) ([]Run, error) {
+	events, err := m.History(marathon.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	results := make(map[int]GameResult)
+	resets := 0
+	endRun := func() {
+		run := Run{Resets: resets}
+		for _, r := range results {
+			run.Results = append(run.Results, r)
+			run.Total.Duration += r.Elapsed.Duration
+		}
+		sort.Slice(run.Results, func(i, j int) bool {
+			return run.Results[i].Game < run.Results[j].Game
+		})
+		runs = append(runs, run)
+		results = make(map[int]GameResult)
+		resets = 0
+	}
+
+	for _, e := range events {
+		switch e.Action {
+		case "finish":
+			r := GameResult{
+				Game:     e.Game,
+				Name:     e.GameName,
+				Elapsed:  e.Elapsed,
+				Splits:   e.Splits,
+				Finished: e.Time,
+			}
+			if e.Game > 0 && e.Game <= len(marathon.Games) {
+				r.Estimate.Duration = marathon.Games[e.Game-1].estimate()
+			}
+			results[e.Game] = r
+		case "resetgame":
+			resets++
+		case "resetmarathon":
+			endRun()
+		}
+	}
+	if len(results) > 0 || resets > 0 || len(runs) == 0 {
+		endRun()
+	}
+	return runs, nil
+}
+
+// ExportRun writes run's results as CSV.
+func ExportRun(w io.Writer, run *Run) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"game", "name", "estimate", "time", "delta", "splits"})
+	for _, r := range run.Results {
+		var splits []string
+		for _, s := range r.Splits {
+			splits = append(splits, s.Name+"="+formatDuration(s.Time.Duration))
+		}
+		delta := ""
+		if r.Estimate.Duration != 0 {
+			delta = renderDelta(r.Elapsed.Duration - r.Estimate.Duration)
+		}
+		cw.Write([]string{
+			strconv.Itoa(r.Game),
+			r.Name,
+			formatDuration(r.Estimate.Duration),
+			formatDuration(r.Elapsed.Duration),
+			delta,
+			strings.Join(splits, " "),
+		})
+	}
+	cw.Write([]string{"", "total", "", formatDuration(run.Total.Duration), "", ""})
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m *MarathonModule) marathonPauseCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+	game := marathon.CurrentGame()
+	if game == nil {
+		cc.Say("Marathon is not running")
+		return nil
+	}
+
+	now := m.bot.Clock().Now()
+	before := marathon.statuses()
+	err = game.Pause(now)
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
+	err = m.saveMarathon(marathon)
+	if err != nil {
+		return err
+	}
+	m.record(marathon, before, "pause", cc.User.Username)
+	cc.Say(fmt.Sprintf("Timer paused at %s.", renderTime(game, now)))
+	return nil
+}
+
+func (m *MarathonModule) marathonResumeCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+	game := marathon.CurrentGame()
+	if game == nil {
+		cc.Say("Marathon is not running")
+		return nil
+	}
+
+	now := m.bot.Clock().Now()
+	before := marathon.statuses()
+	err = game.Resume(now)
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
+	err = m.saveMarathon(marathon)
+	if err != nil {
+		return err
+	}
+	m.record(marathon, before, "resume", cc.User.Username)
+	cc.Say(fmt.Sprintf("Timer resumed at %s.", renderTime(game, now)))
+	return nil
+}
+
+func (m *MarathonModule) marathonSplitCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+
+	before := marathon.statuses()
+	split, err := marathon.Split(strings.Join(args, " "), m.bot.Clock().Now())
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
+	err = m.saveMarathon(marathon)
+	if err != nil {
+		return err
+	}
+	m.record(marathon, before, "split", cc.User.Username)
+	cc.Say(fmt.Sprintf("Split %s: %s", split.Name, formatDuration(split.Time.Duration)))
+	return nil
+}
+
+type HistoryList struct {
+	Events []RunEvent `json:"events"`
+}
+
+type RunList struct {
+	Runs []Run `json:"runs"`
+}
+
+type ExportRunArgs struct {
+	ID int `json:"id"`
+	// Index into the marathon's runs.  Negative counts from the end.
+	Run int `json:"run"`
+}
+
+func (s *MarathonService) History(r *http.Request, id *int, list *HistoryList) error {
+	var err error
+	list.Events, err = s.module.History(*id)
+	return err
+}
+
+func (s *MarathonService) Results(r *http.Request, id *int, list *RunList) error {
+	var marathon Marathon
+	err := s.module.db.One("ID", *id, &marathon)
+	if err != nil {
+		return err
+	}
+	list.Runs, err = s.module.Runs(&marathon)
+	return err
+}
+
+// ExportResults returns a run's results as CSV.
+func (s *MarathonService) ExportResults(r *http.Request, args *ExportRunArgs, reply *string) error {
+	var marathon Marathon
+	err := s.module.db.One("ID", args.ID, &marathon)
+	if err != nil {
+		return err
+	}
+	runs, err := s.module.Runs(&marathon)
+	if err != nil {
+		return err
+	}
+
+	i := args.Run
+	if i < 0 {
+		i += len(runs)
+	}
+	if i < 0 || i >= len(runs) {
+		return fmt.Errorf("no run %d", args.Run)
+	}
+
+	var buf strings.Builder
+	err = ExportRun(&buf, &runs[i])
+	if err != nil {
+		return err
+	}
+	*reply = buf.String()
+	return nil
+}
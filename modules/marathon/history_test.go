@@ -0,0 +1,153 @@
+package marathon
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/konkers/roll"
+	"github.com/konkers/roll/clocktest"
+	"github.com/konkers/roll/rolltest"
+)
+
+func TestMarathonPauseSplit(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := newTestMarathon("a")
+
+	if _, err := m.Split("", now); err == nil {
+		t.Errorf("Split() before the start didn't return an error")
+	}
+	a := m.Games[0]
+	if err := a.Pause(now); err == nil {
+		t.Errorf("Pause() before the start didn't return an error")
+	}
+	m.NextGame(now)
+	if err := a.Resume(now); err == nil {
+		t.Errorf("Resume() of a running game didn't return an error")
+	}
+
+	// Paused time doesn't count toward splits.
+	a.Pause(now.Add(5 * time.Minute))
+	a.Resume(now.Add(15 * time.Minute))
+	a.Pause(now.Add(20 * time.Minute))
+	a.Resume(now.Add(30 * time.Minute))
+	m.Split("", now.Add(40*time.Minute))
+	m.Split("boss", now.Add(50*time.Minute))
+	m.Split("", now.Add(60*time.Minute))
+	want := []Split{
+		{"1", roll.Duration{Duration: 20 * time.Minute}},
+		{"boss", roll.Duration{Duration: 30 * time.Minute}},
+		{"3", roll.Duration{Duration: 40 * time.Minute}},
+	}
+	if !reflect.DeepEqual(a.Splits, want) {
+		t.Errorf("Splits are %v instead of %v", a.Splits, want)
+	}
+}
+
+func TestMarathonRuns(t *testing.T) {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	bot := rolltest.NewBot(t, clock)
+	defer rolltest.Close(bot)
+	mod := rolltest.AddModule(t, bot, "marathon").(*MarathonModule)
+
+	m := newTestMarathon("a", "b")
+	m.ID = 1
+	m.Games[0].Estimate = minutes(60)
+	m.Games[1].Estimate = minutes(30)
+	other := newTestMarathon("other")
+	other.ID = 2
+
+	// do runs a marathon command at the clock's time after d.
+	do := func(marathon *Marathon, d time.Duration, action string, f func(time.Time) error) {
+		clock.Advance(d)
+		before := marathon.statuses()
+		if err := f(clock.Now()); err != nil {
+			t.Fatalf("%s returned error: %v", action, err)
+		}
+		mod.record(marathon, before, action, "rock")
+	}
+	reset := func(time.Time) error { return m.ResetGame() }
+	resetMarathon := func(time.Time) error { return m.ResetMarathon() }
+	split := func(now time.Time) error {
+		_, err := m.Split("", now)
+		return err
+	}
+
+	// The first run finishes a once and b on the second try.
+	do(m, 0, "next", m.NextGame)
+	do(m, 20*time.Minute, "split", split)
+	do(m, 30*time.Minute, "next", m.NextGame)
+	do(m, 40*time.Minute, "resetgame", reset)
+	do(m, 0, "next", m.NextGame)
+	do(other, 0, "next", other.NextGame)
+	do(other, 0, "next", other.NextGame)
+	do(m, 35*time.Minute, "next", m.NextGame)
+	do(m, 0, "resetmarathon", resetMarathon)
+	// The second run only finishes a so far.
+	do(m, 0, "next", m.NextGame)
+	do(m, 10*time.Minute, "next", m.NextGame)
+
+	runs, err := mod.Runs(m)
+	if err != nil {
+		t.Fatalf("Runs() returned error: %v", err)
+	}
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := []Run{
+		{
+			Results: []GameResult{
+				{1, "a", *minutes(60), *minutes(50),
+					[]Split{{"1", *minutes(20)}}, start.Add(50 * time.Minute)},
+				{2, "b", *minutes(30), *minutes(35), nil, start.Add(125 * time.Minute)},
+			},
+			Resets: 1,
+			Total:  *minutes(85),
+		},
+		{
+			Results: []GameResult{
+				{1, "a", *minutes(60), *minutes(10), nil, start.Add(135 * time.Minute)},
+			},
+			Total: *minutes(10),
+		},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("Runs() returned %d runs instead of %d", len(runs), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(runs[i], want[i]) {
+			t.Errorf("Run %d is %+v instead of %+v", i+1, runs[i], want[i])
+		}
+	}
+
+	// A marathon without history has one empty run.
+	runs, err = mod.Runs(newTestMarathon("c"))
+	if err != nil || len(runs) != 1 || len(runs[0].Results) != 0 {
+		t.Errorf("Runs() without history returned %v, %v", runs, err)
+	}
+}
+
+func TestExportRun(t *testing.T) {
+	run := &Run{
+		Results: []GameResult{
+			{Game: 1, Name: "a", Estimate: *minutes(60), Elapsed: *minutes(50),
+				Splits: []Split{{"1", *minutes(20)}, {"boss", *minutes(45)}}},
+			{Game: 2, Name: "b, the sequel", Estimate: *minutes(30), Elapsed: *minutes(35)},
+			{Game: 3, Name: "c", Elapsed: *minutes(5)},
+		},
+		Total: *minutes(90),
+	}
+
+	var b bytes.Buffer
+	err := ExportRun(&b, run)
+	if err != nil {
+		t.Fatalf("ExportRun() returned error: %v", err)
+	}
+	want := "game,name,estimate,time,delta,splits\n" +
+		"1,a,1:00:00,0:50:00,-0:10:00,1=0:20:00 boss=0:45:00\n" +
+		"2,\"b, the sequel\",0:30:00,0:35:00,+0:05:00,\n" +
+		"3,c,0:00:00,0:05:00,,\n" +
+		",total,,1:30:00,,\n"
+	if b.String() != want {
+		t.Errorf("ExportRun() wrote\n%s\ninstead of\n%s", b.String(), want)
+	}
+}
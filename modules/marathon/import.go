@@ -0,0 +1,377 @@
+package marathon
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/konkers/roll"
+)
+
+func stringPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func durationPtr(d time.Duration) *roll.Duration {
+	if d == 0 {
+		return nil
+	}
+	return &roll.Duration{Duration: d}
+}
+
+type horaroExport struct {
+	Schedule struct {
+		Name    string   `json:"name"`
+		Start   string   `json:"start"`
+		SetupT  int      `json:"setup_t"`
+		Columns []string `json:"columns"`
+		Items   []struct {
+			LengthT int       `json:"length_t"`
+			Data    []*string `json:"data"`
+		} `json:"items"`
+	} `json:"schedule"`
+}
+
+// Horaro cells can be markdown links.
+var markdownLinkRE = regexp.MustCompile(`^\[(.*)\]\((.*)\)$`)
+
+// findColumn returns the index of the first column whose name contains
+// one of names or -1.
+func findColumn(columns []string, names ...string) int {
+	for i, c := range columns {
+		c = strings.ToLower(c)
+		for _, name := range names {
+			if strings.Contains(c, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ImportHoraro builds a marathon from a Horaro schedule JSON export.
+func ImportHoraro(r io.Reader) (*Marathon, error) {
+	var export horaroExport
+	err := json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse horaro export: %v", err)
+	}
+	schedule := &export.Schedule
+
+	marathon := &Marathon{
+		Name:  stringPtr(schedule.Name),
+		Setup: durationPtr(time.Duration(schedule.SetupT) * time.Second),
+	}
+	if schedule.Start != "" {
+		start, err := time.Parse(time.RFC3339, schedule.Start)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse start time %s: %v", schedule.Start, err)
+		}
+		marathon.StartTime = &start
+	}
+
+	gameCol := findColumn(schedule.Columns, "game")
+	if gameCol < 0 {
+		gameCol = 0
+	}
+	categoryCol := findColumn(schedule.Columns, "category")
+	systemCol := findColumn(schedule.Columns, "platform", "system", "console")
+
+	cell := func(data []*string, col int) (string, string) {
+		if col < 0 || col >= len(data) || data[col] == nil {
+			return "", ""
+		}
+		text := strings.TrimSpace(*data[col])
+		if m := markdownLinkRE.FindStringSubmatch(text); m != nil {
+			return m[1], m[2]
+		}
+		return text, ""
+	}
+
+	for _, item := range schedule.Items {
+		name, link := cell(item.Data, gameCol)
+		category, _ := cell(item.Data, categoryCol)
+		system, _ := cell(item.Data, systemCol)
+		if name == "" {
+			continue
+		}
+		marathon.Games = append(marathon.Games, &MarathonGame{
+			Name:     stringPtr(name),
+			Link:     stringPtr(link),
+			Category: stringPtr(category),
+			System:   stringPtr(system),
+			Estimate: durationPtr(time.Duration(item.LengthT) * time.Second),
+		})
+	}
+	return marathon, nil
+}
+
+type liveSplitRun struct {
+	GameName     string `xml:"GameName"`
+	CategoryName string `xml:"CategoryName"`
+	Segments     []struct {
+		SplitTimes []struct {
+			Name     string `xml:"name,attr"`
+			RealTime string `xml:"RealTime"`
+		} `xml:"SplitTimes>SplitTime"`
+		BestSegmentTime string `xml:"BestSegmentTime>RealTime"`
+	} `xml:"Segments>Segment"`
+}
+
+// parseLiveSplitTime parses LiveSplit's hh:mm:ss.fffffff times.
+func parseLiveSplitTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("bad time %s", s)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("bad time %s", s)
+	}
+	mins, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("bad time %s", s)
+	}
+	secs, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad time %s", s)
+	}
+	d := time.Duration(hours)*time.Hour + time.Duration(mins)*time.Minute +
+		time.Duration(secs*float64(time.Second))
+	return d.Round(time.Second), nil
+}
+
+// ImportLiveSplit builds a game from a LiveSplit .lss file.  The estimate
+// is the personal best, or the sum of best segments if there is no PB.
+func ImportLiveSplit(r io.Reader) (*MarathonGame, error) {
+	var run liveSplitRun
+	err := xml.NewDecoder(r).Decode(&run)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse splits: %v", err)
+	}
+	if run.GameName == "" {
+		return nil, fmt.Errorf("splits have no game name")
+	}
+
+	var estimate time.Duration
+	if n := len(run.Segments); n > 0 {
+		for _, st := range run.Segments[n-1].SplitTimes {
+			if st.Name == "Personal Best" && st.RealTime != "" {
+				estimate, err = parseLiveSplitTime(st.RealTime)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if estimate == 0 {
+		for _, seg := range run.Segments {
+			if seg.BestSegmentTime == "" {
+				estimate = 0
+				break
+			}
+			d, err := parseLiveSplitTime(seg.BestSegmentTime)
+			if err != nil {
+				return nil, err
+			}
+			estimate += d
+		}
+	}
+
+	return &MarathonGame{
+		Name:     stringPtr(run.GameName),
+		Category: stringPtr(run.CategoryName),
+		Estimate: durationPtr(estimate),
+	}, nil
+}
+
+var csvHeader = []string{"name", "category", "system", "twitch_game", "link", "estimate", "setup"}
+
+// ImportCSV builds a marathon's games from CSV with csvHeader's columns.
+// Only the name column is required.
+func ImportCSV(r io.Reader) (*Marathon, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+
+	cols := make(map[string]int)
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, fmt.Errorf("no name column")
+	}
+	field := func(record []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	duration := func(record []string, name string, line int) (*roll.Duration, error) {
+		s := field(record, name)
+		if s == "" {
+			return nil, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad %s %s", line, name, s)
+		}
+		return durationPtr(d), nil
+	}
+
+	marathon := &Marathon{}
+	for i, record := range records[1:] {
+		line := i + 2
+		game := &MarathonGame{
+			Name:       stringPtr(field(record, "name")),
+			Category:   stringPtr(field(record, "category")),
+			System:     stringPtr(field(record, "system")),
+			TwitchGame: stringPtr(field(record, "twitch_game")),
+			Link:       stringPtr(field(record, "link")),
+		}
+		if game.Name == nil {
+			return nil, fmt.Errorf("line %d: no name", line)
+		}
+		game.Estimate, err = duration(record, "estimate", line)
+		if err != nil {
+			return nil, err
+		}
+		game.Setup, err = duration(record, "setup", line)
+		if err != nil {
+			return nil, err
+		}
+		marathon.Games = append(marathon.Games, game)
+	}
+	return marathon, nil
+}
+
+// ExportCSV writes marathon's games in the format ImportCSV reads.
+func ExportCSV(w io.Writer, marathon *Marathon) error {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	dur := func(d *roll.Duration) string {
+		if d == nil {
+			return ""
+		}
+		return d.Duration.String()
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, game := range marathon.Games {
+		cw.Write([]string{
+			str(game.Name),
+			str(game.Category),
+			str(game.System),
+			str(game.TwitchGame),
+			str(game.Link),
+			dur(game.Estimate),
+			dur(game.Setup),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Import builds a marathon from files in format "horaro", "lss" or
+// "csv".  Horaro and CSV take a single file.  Each LiveSplit file is one
+// game.  A non-empty name overrides the imported one.
+func Import(format string, name string, files []io.Reader) (*Marathon, error) {
+	var marathon *Marathon
+	var err error
+
+	switch format {
+	case "horaro", "csv":
+		if len(files) != 1 {
+			return nil, fmt.Errorf("%s import takes one file", format)
+		}
+		if format == "horaro" {
+			marathon, err = ImportHoraro(files[0])
+		} else {
+			marathon, err = ImportCSV(files[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+	case "lss":
+		marathon = &Marathon{}
+		for _, f := range files {
+			game, err := ImportLiveSplit(f)
+			if err != nil {
+				return nil, err
+			}
+			marathon.Games = append(marathon.Games, game)
+		}
+	default:
+		return nil, fmt.Errorf("unknown import format %s", format)
+	}
+
+	if name != "" {
+		marathon.Name = &name
+	}
+	return marathon, nil
+}
+
+type ImportArgs struct {
+	// Empty for the default channel.
+	Channel string `json:"channel"`
+	// "horaro", "lss" or "csv".
+	Format string   `json:"format"`
+	Name   string   `json:"name"`
+	Files  []string `json:"files"`
+}
+
+// Import creates a marathon from imported files.
+func (s *MarathonService) Import(r *http.Request, args *ImportArgs, reply *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+
+	var files []io.Reader
+	for _, f := range args.Files {
+		files = append(files, strings.NewReader(f))
+	}
+	marathon, err := Import(args.Format, args.Name, files)
+	if err != nil {
+		*reply = -1
+		return err
+	}
+	marathon.Channel = args.Channel
+	return s.New(r, marathon, reply)
+}
+
+// ExportCSV returns a marathon's games as CSV.
+func (s *MarathonService) ExportCSV(r *http.Request, id *int, reply *string) error {
+	var marathon Marathon
+	err := s.module.db.One("ID", *id, &marathon)
+	if err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	err = ExportCSV(&buf, &marathon)
+	if err != nil {
+		return err
+	}
+	*reply = buf.String()
+	return nil
+}
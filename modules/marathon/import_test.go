@@ -0,0 +1,188 @@
+package marathon
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestFile(t *testing.T, name string) *os.File {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Can't open test file: %v", err)
+	}
+	return f
+}
+
+func expectGames(t *testing.T, what string, got []*MarathonGame, want []*MarathonGame) {
+	if len(got) != len(want) {
+		t.Fatalf("%s imported %d games instead of %d", what, len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("%s imported game %d as %s instead of %s", what, i+1,
+				formatGame(got[i]), formatGame(want[i]))
+		}
+	}
+}
+
+// formatGame returns g's imported fields for test messages.
+func formatGame(g *MarathonGame) string {
+	var b bytes.Buffer
+	ExportCSV(&b, &Marathon{Games: []*MarathonGame{g}})
+	return strings.TrimPrefix(b.String(), strings.Join(csvHeader, ",")+"\n")
+}
+
+var (
+	superMetroid = &MarathonGame{
+		Name:     stringPtr("Super Metroid"),
+		Category: stringPtr("Any%"),
+		Estimate: durationPtr(time.Hour + 2*time.Minute + 3*time.Second),
+	}
+	tetris = &MarathonGame{
+		Name:     stringPtr("Tetris"),
+		Category: stringPtr("Level 29"),
+		Estimate: durationPtr(50 * time.Minute),
+	}
+)
+
+func TestImportHoraro(t *testing.T) {
+	f := openTestFile(t, "horaro.json")
+	defer f.Close()
+
+	marathon, err := ImportHoraro(f)
+	if err != nil {
+		t.Fatalf("ImportHoraro() returned error: %v", err)
+	}
+	start := time.Date(2019, 1, 5, 16, 0, 0, 0, time.UTC)
+	if *marathon.Name != "Winter Marathon" || !marathon.StartTime.Equal(start) ||
+		marathon.Setup.Duration != 10*time.Minute {
+		t.Errorf("ImportHoraro() returned %s starting %v with %v setup",
+			*marathon.Name, marathon.StartTime, marathon.Setup)
+	}
+	// Rows without a game are left out.
+	expectGames(t, "ImportHoraro()", marathon.Games, []*MarathonGame{
+		{
+			Name:     stringPtr("Super Metroid"),
+			Link:     stringPtr("https://example.com/sm"),
+			Category: stringPtr("Any%"),
+			System:   stringPtr("SNES"),
+			Estimate: durationPtr(90 * time.Minute),
+		},
+		{
+			Name:     stringPtr("Tetris"),
+			System:   stringPtr("Game Boy"),
+			Estimate: durationPtr(45 * time.Minute),
+		},
+	})
+}
+
+func TestImportLiveSplit(t *testing.T) {
+	for _, test := range []struct {
+		file string
+		game *MarathonGame
+	}{
+		// The personal best is the estimate.
+		{"pb.lss", superMetroid},
+		// Without one, the best segments add up to the estimate.
+		{"sob.lss", tetris},
+	} {
+		f := openTestFile(t, test.file)
+		game, err := ImportLiveSplit(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("ImportLiveSplit(%s) returned error: %v", test.file, err)
+			continue
+		}
+		expectGames(t, "ImportLiveSplit("+test.file+")",
+			[]*MarathonGame{game}, []*MarathonGame{test.game})
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	games := []*MarathonGame{
+		{
+			Name:       stringPtr("Super Metroid"),
+			Category:   stringPtr("Any%, no glitches"),
+			System:     stringPtr("SNES"),
+			TwitchGame: stringPtr("Super Metroid"),
+			Link:       stringPtr("https://example.com/sm"),
+			Estimate:   durationPtr(90 * time.Minute),
+			Setup:      durationPtr(15 * time.Minute),
+		},
+		{Name: stringPtr(`"Quoted" game`)},
+	}
+
+	var b bytes.Buffer
+	err := ExportCSV(&b, &Marathon{Games: games})
+	if err != nil {
+		t.Fatalf("ExportCSV() returned error: %v", err)
+	}
+	marathon, err := ImportCSV(&b)
+	if err != nil {
+		t.Fatalf("ImportCSV() returned error: %v", err)
+	}
+	expectGames(t, "CSV round trip", marathon.Games, games)
+
+	// Columns can come in any order and only the name is required.
+	marathon, err = ImportCSV(strings.NewReader("Estimate, Name\n50m, Tetris\n"))
+	if err != nil {
+		t.Fatalf("ImportCSV() returned error: %v", err)
+	}
+	expectGames(t, "ImportCSV()", marathon.Games, []*MarathonGame{
+		{Name: stringPtr("Tetris"), Estimate: durationPtr(50 * time.Minute)},
+	})
+}
+
+func TestImportErrors(t *testing.T) {
+	for _, test := range []struct {
+		format string
+		files  []string
+	}{
+		{"xls", []string{"name\ngame\n"}},
+		{"csv", nil},
+		{"csv", []string{"name\na\n", "name\nb\n"}},
+		{"csv", []string{""}},
+		{"csv", []string{"game,estimate\na,1h\n"}},
+		{"csv", []string{"name,estimate\na,an hour\n"}},
+		{"csv", []string{"name,estimate\n,1h\n"}},
+		{"horaro", []string{"<html>"}},
+		{"horaro", []string{`{"schedule": {"start": "tomorrow"}}`}},
+		{"lss", []string{"<Run><CategoryName>Any%</CategoryName></Run>"}},
+		{"lss", []string{"<Run><GameName>a</GameName><Segments><Segment><SplitTimes>" +
+			`<SplitTime name="Personal Best"><RealTime>1:02</RealTime></SplitTime>` +
+			"</SplitTimes></Segment></Segments></Run>"}},
+	} {
+		var files []io.Reader
+		for _, f := range test.files {
+			files = append(files, strings.NewReader(f))
+		}
+		if _, err := Import(test.format, "", files); err == nil {
+			t.Errorf("Import(%s, %q) didn't return an error", test.format, test.files)
+		}
+	}
+}
+
+func TestImportLiveSplitFiles(t *testing.T) {
+	var files []io.Reader
+	for _, name := range []string{"pb.lss", "sob.lss"} {
+		f := openTestFile(t, name)
+		defer f.Close()
+		files = append(files, f)
+	}
+
+	// Each file is one game, in order.
+	marathon, err := Import("lss", "Splits Marathon", files)
+	if err != nil {
+		t.Fatalf("Import() returned error: %v", err)
+	}
+	if marathon.Name == nil || *marathon.Name != "Splits Marathon" {
+		t.Errorf("Import() named the marathon %v", marathon.Name)
+	}
+	expectGames(t, "Import()", marathon.Games, []*MarathonGame{superMetroid, tetris})
+}
@@ -2,7 +2,9 @@ package marathon
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/asdine/storm"
@@ -15,22 +17,50 @@ const (
 	GameStatusNotStarted = MarathonGameStatus(iota)
 	GameStatusRunning
 	GameStatusFinished
+	GameStatusSkipped
 )
 
 type MarathonGame struct {
 	Name        *string             `json:"name"`
+	Category    *string             `json:"category"`
 	TwitchGame  *string             `json:"twitch_game"`
 	Link        *string             `json:"link"`
 	System      *string             `json:"system"`
 	Status      *MarathonGameStatus `json:"status"`
 	StartedTime *time.Time          `json:"started_time"`
 	EndedTime   *time.Time          `json:"ended_time"`
+	Estimate    *roll.Duration      `json:"estimate"`
+	// Setup time after the game.  Defaults to the marathon's Setup.
+	Setup *roll.Duration `json:"setup"`
+	// When the timer was paused, if it is.
+	Paused *time.Time `json:"paused"`
+	// Time spent paused, not counting the current pause.
+	PausedTotal *roll.Duration `json:"paused_total"`
+	Splits      []Split        `json:"splits"`
 }
 
 type Marathon struct {
-	ID    int             `json:"id" storm:"id,increment"`
-	Name  *string         `json:"name"`
-	Games []*MarathonGame `json:"games"`
+	ID      int             `json:"id" storm:"id,increment"`
+	Channel string          `json:"channel" storm:"index"`
+	Name    *string         `json:"name"`
+	Games   []*MarathonGame `json:"games"`
+	// When the marathon is scheduled to start.
+	StartTime *time.Time `json:"start_time"`
+	// Default setup time between games.
+	Setup *roll.Duration `json:"setup"`
+}
+
+// ScheduleEntry is a game's place in the marathon schedule.
+type ScheduleEntry struct {
+	Number int           `json:"number"`
+	Game   *MarathonGame `json:"game"`
+	// Planned start from the scheduled start time and estimates.  Zero if
+	// the marathon has no start time.
+	Scheduled time.Time `json:"scheduled"`
+	// Actual start of started games and projected start of the rest.
+	Projected time.Time `json:"projected"`
+	// How far Projected is behind Scheduled.  Negative when ahead.
+	Delta time.Duration `json:"delta"`
 }
 
 type MarathonModule struct {
@@ -58,18 +88,43 @@ func NewMarathonModule(bot *roll.Bot, db storm.Node) (roll.Module, error) {
 
 	module.service = NewMarathonService(module)
 
-	module.marathonCmd.AddCommand("next", "go to next game", module.marathonNextCommand, 10)
-	module.marathonCmd.AddCommand("resetgame", "reset the game", module.marathonResetGameCommand, 10)
-	module.marathonCmd.AddCommand("resetmarathon", "reset the marathon", module.marathonResetMarathonCommand, 10)
+	module.marathonCmd.AddCommand("next", "go to next game", module.marathonNextCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("resetgame", "reset the game", module.marathonResetGameCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("resetmarathon", "reset the marathon", module.marathonResetMarathonCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("prev", "go back to the previous game", module.marathonPrevCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("skip", "skip the current game", module.marathonSkipCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("goto", "go to game <n>", module.marathonGotoCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("status", "show marathon progress", module.marathonStatusCommand, 0)
+	module.marathonCmd.AddCommand("eta", "show when the next game starts", module.marathonEtaCommand, 0)
+	module.marathonCmd.AddCommand("list", "list marathons", module.marathonListCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("use", "make marathon <id> active", module.marathonUseCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("pause", "pause the timer", module.marathonPauseCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("resume", "resume the timer", module.marathonResumeCommand, roll.UserLevelModerator)
+	module.marathonCmd.AddCommand("split", "record a split [name]", module.marathonSplitCommand, roll.UserLevelModerator)
+
+	err := bot.AddCommand("marathon", "Shows the current marathon game.", module.marathonCommand, 0)
+	if err != nil {
+		return nil, err
+	}
+	bot.AddSubEngine("marathon", module.marathonCmd)
 
 	if err := bot.AddTemplateFunc("status", renderStatus); err != nil {
 		return nil, err
 	}
 
-	if err := bot.AddTemplateFunc("time", renderTime); err != nil {
+	renderNow := func(game *MarathonGame) string {
+		return renderTime(game, bot.Clock().Now())
+	}
+	if err := bot.AddTemplateFunc("time", renderNow); err != nil {
+		return nil, err
+	}
+
+	if err := bot.AddTemplateFunc("delta", renderDelta); err != nil {
 		return nil, err
 	}
 
+	module.migrateChannels()
+
 	return module, nil
 }
 
@@ -81,6 +136,20 @@ func (m *MarathonModule) Stop() error {
 	return nil
 }
 
+func (m *MarathonModule) GetRPCService() interface{} {
+	return m.service
+}
+
+func (m *MarathonModule) GetPublicHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/overlay", m.overlayHandler)
+	mux.HandleFunc("/archive", m.archiveHandler)
+	mux.HandleFunc("/", m.scheduleHandler)
+	return mux
+}
+
+// CurrentMarathon is the active marathon of channels that haven't chosen
+// one.
 const CurrentMarathon = 1
 
 func NewMarathonService(module *MarathonModule) *MarathonService {
@@ -94,6 +163,9 @@ func (s *MarathonService) New(r *http.Request, args *Marathon, reply *int) error
 		return fmt.Errorf("access denied")
 	}
 	args.ID = 0
+	if args.Channel == "" {
+		args.Channel = s.module.bot.DefaultChannel()
+	}
 	err := s.module.db.Save(args)
 	if err != nil {
 		*reply = -1
@@ -129,20 +201,33 @@ func renderStatus(status *MarathonGameStatus) string {
 		return "running"
 	case GameStatusFinished:
 		return "finished"
+	case GameStatusSkipped:
+		return "skipped"
 	default:
 		return "???"
 	}
 }
 
-func renderTime(game *MarathonGame) string {
-	var d time.Duration
+func renderTime(game *MarathonGame, now time.Time) string {
 	if game.StartedTime == nil {
 		return "?:??:??"
-	} else if game.EndedTime == nil {
-		d = time.Now().Sub(*game.StartedTime)
-	} else {
-		d = game.EndedTime.Sub(*game.StartedTime)
 	}
+	return formatDuration(game.Elapsed(now))
+}
+
+// renderDelta shows how far ahead or behind schedule d is.
+func renderDelta(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return "+" + formatDuration(d)
+	case d <= -time.Second:
+		return "-" + formatDuration(-d)
+	default:
+		return "on time"
+	}
+}
+
+func formatDuration(d time.Duration) string {
 	hours := d.Truncate(time.Hour)
 	d -= hours
 	mins := d.Truncate(time.Minute)
@@ -155,18 +240,89 @@ func (s *MarathonService) Get(r *http.Request, id *int, marathon *Marathon) erro
 	return s.module.db.One("ID", *id, marathon)
 }
 
-func (m *MarathonModule) showMarathon(cc *roll.CommandContext) error {
+type ScheduleList struct {
+	Entries []ScheduleEntry `json:"entries"`
+}
+
+func (s *MarathonService) Schedule(r *http.Request, id *int, list *ScheduleList) error {
 	var marathon Marathon
-	cur := CurrentMarathon
-	err := m.service.Get(nil, &cur, &marathon)
+	err := s.module.db.One("ID", *id, &marathon)
+	if err != nil {
+		return err
+	}
+	list.Entries = marathon.Schedule(s.module.bot.Clock().Now())
+	return nil
+}
+
+func (m *MarathonModule) scheduleHandler(w http.ResponseWriter, req *http.Request) {
+	marathon, err := m.requestMarathon(req)
+	if err != nil {
+		log.Printf("Can't get marathon: %v", err)
+		http.Error(w, "Can't get marathon", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Marathon *Marathon
+		Current  *MarathonGame
+		Schedule []ScheduleEntry
+	}{
+		Marathon: marathon,
+		Current:  marathon.CurrentGame(),
+		Schedule: marathon.Schedule(m.bot.Clock().Now()),
+	}
+	err = m.bot.ExecuteTemplate(w, "marathon_schedule.html", data)
+	if err != nil {
+		log.Printf("Can't render marathon schedule: %v", err)
+	}
+}
+
+// overlayHandler serves a page for use as an OBS browser source.
+func (m *MarathonModule) overlayHandler(w http.ResponseWriter, req *http.Request) {
+	marathon, err := m.requestMarathon(req)
+	if err != nil {
+		log.Printf("Can't get marathon: %v", err)
+		http.Error(w, "Can't get marathon", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Marathon *Marathon
+		Current  *MarathonGame
+		Number   int
+		Total    int
+		Next     *MarathonGame
+		Elapsed  time.Duration
+	}{
+		Marathon: marathon,
+		Current:  marathon.CurrentGame(),
+		Number:   marathon.CurrentGameIndex() + 1,
+		Total:    len(marathon.Games),
+		Next:     marathon.UpcomingGame(),
+	}
+	if data.Current != nil {
+		data.Elapsed = data.Current.Elapsed(m.bot.Clock().Now())
+	}
+	err = m.bot.ExecuteTemplate(w, "marathon_overlay.html", data)
+	if err != nil {
+		log.Printf("Can't render marathon overlay: %v", err)
+	}
+}
+
+func (m *MarathonModule) showMarathon(cc *roll.CommandContext) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err == storm.ErrNotFound {
+		cc.Say("There's no marathon.")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 	game := marathon.CurrentGame()
 	if game == nil {
-		cc.IRC.Say(cc.Channel, fmt.Sprintf("Marathon is not running"))
+		cc.Say(fmt.Sprintf("Marathon is not running"))
 	} else {
-		cc.IRC.Say(cc.Channel, fmt.Sprintf("Current game is: %s", *game.Name))
+		cc.Say(fmt.Sprintf("Current game is: %s", *game.Name))
 	}
 	return nil
 }
@@ -179,68 +335,531 @@ func (m *MarathonModule) marathonCommand(cc *roll.CommandContext, args []string)
 	return m.marathonCmd.Exec(cc, cc.UserLevel, args)
 }
 
-func (m *MarathonModule) marathonResetGameCommand(cc *roll.CommandContext, args []string) error {
+// loadMarathon returns channel's active marathon.
+func (m *MarathonModule) loadMarathon(channel string) (*Marathon, error) {
 	var marathon Marathon
-	cur := CurrentMarathon
+	cur := m.ActiveMarathon(channel)
 	err := m.service.Get(nil, &cur, &marathon)
+	if err != nil {
+		return nil, err
+	}
+	// Channels can only run their own marathons.
+	if marathon.Channel != channel {
+		return nil, storm.ErrNotFound
+	}
+	return &marathon, nil
+}
+
+func (m *MarathonModule) saveMarathon(marathon *Marathon) error {
+	reply := 0
+	return m.service.Update(nil, marathon, &reply)
+}
+
+// setGame points the channel's twitch game at game.
+func (m *MarathonModule) setGame(cc *roll.CommandContext, game *MarathonGame) {
+	name := *game.Name
+	if game.TwitchGame != nil {
+		name = *game.TwitchGame
+	}
+	err := cc.API.SetChannelGame(cc.Channel, name)
+	if err != nil {
+		log.Printf("Can't set game to %s: %v", name, err)
+	}
+}
+
+// announceGame says which game is now running and updates the twitch game.
+func (m *MarathonModule) announceGame(cc *roll.CommandContext, marathon *Marathon) {
+	game := marathon.CurrentGame()
+	if game == nil {
+		cc.Say("Marathon is not running")
+		return
+	}
+	cc.Say(fmt.Sprintf("%s started!", *game.Name))
+	m.setGame(cc, game)
+}
+
+func (m *MarathonModule) marathonPrevCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+
+	before := marathon.statuses()
+	err = marathon.PrevGame()
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
+	err = m.saveMarathon(marathon)
+	if err != nil {
+		return err
+	}
+	m.record(marathon, before, "prev", cc.User.Username)
+	m.announceGame(cc, marathon)
+	return nil
+}
+
+func (m *MarathonModule) marathonSkipCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err == storm.ErrNotFound {
+		cc.Say("There's no marathon.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	before := marathon.statuses()
+	skipped, err := marathon.SkipGame(m.bot.Clock().Now())
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
+	err = m.saveMarathon(marathon)
+	if err != nil {
+		return err
+	}
+	m.record(marathon, before, "skip", cc.User.Username)
+	cc.Say(fmt.Sprintf("%s skipped.", *skipped.Name))
+	if marathon.CurrentGame() != nil {
+		m.announceGame(cc, marathon)
+	}
+	return nil
+}
+
+func (m *MarathonModule) marathonGotoCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) != 1 {
+		cc.Say(fmt.Sprintf("Usage: %smarathon goto <n>", cc.Prefix))
+		return nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s is not a game number.", args[0]))
+		return nil
+	}
+
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+	before := marathon.statuses()
+	err = marathon.GotoGame(n-1, m.bot.Clock().Now())
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
+	err = m.saveMarathon(marathon)
+	if err != nil {
+		return err
+	}
+	m.record(marathon, before, "goto", cc.User.Username)
+	m.announceGame(cc, marathon)
+	return nil
+}
+
+func (m *MarathonModule) marathonStatusCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+
+	i := marathon.CurrentGameIndex()
+	if i < 0 {
+		cc.Say(fmt.Sprintf("Marathon is not running.  %d of %d games done.",
+			marathon.GamesDone(), len(marathon.Games)))
+		return nil
+	}
+
+	game := marathon.Games[i]
+	status := fmt.Sprintf("Game %d of %d: %s (%s).", i+1, len(marathon.Games),
+		*game.Name, renderTime(game, m.bot.Clock().Now()))
+	if next := marathon.UpcomingGame(); next != nil {
+		status += fmt.Sprintf(" Next up: %s.", *next.Name)
+	}
+	cc.Say(status)
+	return nil
+}
+
+func (m *MarathonModule) marathonEtaCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
 	if err != nil {
 		return err
 	}
 
+	next := marathon.UpcomingGame()
+	if next == nil {
+		cc.Say("There are no more games.")
+		return nil
+	}
+
+	now := m.bot.Clock().Now()
+	if marathon.hasEstimates() {
+		for _, e := range marathon.Schedule(now) {
+			if e.Game != next {
+				continue
+			}
+			msg := fmt.Sprintf("Next up: %s at %s (in %s).", *next.Name,
+				e.Projected.Format("15:04 MST"), formatDuration(e.Projected.Sub(now)))
+			if !e.Scheduled.IsZero() {
+				msg += fmt.Sprintf(" Schedule: %s.", renderDelta(e.Delta))
+			}
+			cc.Say(msg)
+			return nil
+		}
+	}
+
+	eta, ok := marathon.AverageETA(now)
+	if !ok {
+		cc.Say(fmt.Sprintf("Next up: %s.", *next.Name))
+		return nil
+	}
+	cc.Say(fmt.Sprintf("Next up: %s in about %s.", *next.Name, formatDuration(eta)))
+	return nil
+}
+
+func (m *MarathonModule) marathonResetGameCommand(cc *roll.CommandContext, args []string) error {
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err != nil {
+		return err
+	}
+
+	before := marathon.statuses()
 	marathon.ResetGame()
-	reply := 0
-	err = m.service.Update(nil, &marathon, &reply)
+	err = m.saveMarathon(marathon)
 	if err != nil {
 		return err
 	}
-	cc.IRC.Say(cc.Channel, "RESET!")
+	m.record(marathon, before, "resetgame", cc.User.Username)
+	cc.Say("RESET!")
 	return nil
 }
 
 func (m *MarathonModule) marathonResetMarathonCommand(cc *roll.CommandContext, args []string) error {
-	var marathon Marathon
-	cur := CurrentMarathon
-	err := m.service.Get(nil, &cur, &marathon)
+	marathon, err := m.loadMarathon(cc.Channel)
 	if err != nil {
 		return err
 	}
 
+	before := marathon.statuses()
 	marathon.ResetMarathon()
-	reply := 0
-	err = m.service.Update(nil, &marathon, &reply)
+	err = m.saveMarathon(marathon)
 	if err != nil {
 		return err
 	}
-	cc.IRC.Say(cc.Channel, "RESET!")
+	m.record(marathon, before, "resetmarathon", cc.User.Username)
+	cc.Say("RESET!")
 	return nil
 }
 
 func (m *MarathonModule) marathonNextCommand(cc *roll.CommandContext, args []string) error {
-	var marathon Marathon
-	cur := CurrentMarathon
-	err := m.service.Get(nil, &cur, &marathon)
+	marathon, err := m.loadMarathon(cc.Channel)
+	if err == storm.ErrNotFound {
+		cc.Say("There's no marathon.")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
+	before := marathon.statuses()
 	prevGame := marathon.CurrentGame()
-	marathon.NextGame()
+	err = marathon.NextGame(m.bot.Clock().Now())
+	if err != nil {
+		cc.Say(err.Error())
+		return nil
+	}
 	nextGame := marathon.CurrentGame()
-	reply := 0
-	err = m.service.Update(nil, &marathon, &reply)
+	err = m.saveMarathon(marathon)
 	if err != nil {
 		return err
 	}
+	m.record(marathon, before, "next", cc.User.Username)
 
 	if prevGame != nil {
-		cc.IRC.Say(cc.Channel, fmt.Sprintf("%s complete!", *prevGame.Name))
+		cc.Say(fmt.Sprintf("%s complete!", *prevGame.Name))
 	}
 	if nextGame != nil {
-		cc.IRC.Say(cc.Channel, fmt.Sprintf("%s started!", *nextGame.Name))
-		if nextGame.TwitchGame != nil {
-			cc.API.SetChannelGame(cc.Channel, *nextGame.TwitchGame)
-		} else {
-			cc.API.SetChannelGame(cc.Channel, *nextGame.Name)
+		cc.Say(fmt.Sprintf("%s started!", *nextGame.Name))
+		m.setGame(cc, nextGame)
+	} else {
+		cc.Say("Marathon complete!")
+	}
+	return nil
+}
+
+func (g *MarathonGame) status() MarathonGameStatus {
+	if g.Status == nil {
+		return GameStatusNotStarted
+	}
+	return *g.Status
+}
+
+func (g *MarathonGame) setStatus(status MarathonGameStatus) {
+	g.Status = &status
+}
+
+// reset returns g to not started.
+func (g *MarathonGame) reset() {
+	g.setStatus(GameStatusNotStarted)
+	g.StartedTime = nil
+	g.EndedTime = nil
+	g.Paused = nil
+	g.PausedTotal = nil
+	g.Splits = nil
+}
+
+// finish stops g's timer at now.
+func (g *MarathonGame) finish(now time.Time) {
+	if g.Paused != nil {
+		g.Resume(now)
+	}
+	g.setStatus(GameStatusFinished)
+	g.EndedTime = &now
+}
+
+// Elapsed returns how long g has been, or was, running at now, not
+// counting time paused.
+func (g *MarathonGame) Elapsed(now time.Time) time.Duration {
+	if g.StartedTime == nil {
+		return 0
+	}
+	end := now
+	if g.EndedTime != nil {
+		end = *g.EndedTime
+	}
+	if g.Paused != nil && g.Paused.Before(end) {
+		end = *g.Paused
+	}
+	d := end.Sub(*g.StartedTime)
+	if g.PausedTotal != nil {
+		d -= g.PausedTotal.Duration
+	}
+	return d
+}
+
+// CurrentGameIndex returns the index of the running game or -1.
+func (m *Marathon) CurrentGameIndex() int {
+	for i, game := range m.Games {
+		if game.status() == GameStatusRunning {
+			return i
+		}
+	}
+	return -1
+}
+
+// UpcomingGame returns the first game that hasn't started.
+func (m *Marathon) UpcomingGame() *MarathonGame {
+	for _, game := range m.Games {
+		if game.status() == GameStatusNotStarted {
+			return game
+		}
+	}
+	return nil
+}
+
+// GamesDone returns the number of finished or skipped games.
+func (m *Marathon) GamesDone() int {
+	done := 0
+	for _, game := range m.Games {
+		if s := game.status(); s == GameStatusFinished || s == GameStatusSkipped {
+			done++
+		}
+	}
+	return done
+}
+
+// AverageETA estimates how long after now the upcoming game starts from
+// the average length of the finished games.
+func (m *Marathon) AverageETA(now time.Time) (time.Duration, bool) {
+	var total time.Duration
+	finished := 0
+	for _, game := range m.Games {
+		if game.status() == GameStatusFinished {
+			total += game.Elapsed(now)
+			finished++
+		}
+	}
+	if finished == 0 {
+		return 0, false
+	}
+	average := total / time.Duration(finished)
+
+	cur := m.CurrentGame()
+	if cur == nil {
+		return 0, true
+	}
+	eta := average - cur.Elapsed(now)
+	if eta < 0 {
+		eta = 0
+	}
+	return eta, true
+}
+
+func (g *MarathonGame) estimate() time.Duration {
+	if g.Estimate == nil {
+		return 0
+	}
+	return g.Estimate.Duration
+}
+
+func (m *Marathon) setup(g *MarathonGame) time.Duration {
+	if g.Setup != nil {
+		return g.Setup.Duration
+	}
+	if m.Setup != nil {
+		return m.Setup.Duration
+	}
+	return 0
+}
+
+func (m *Marathon) hasEstimates() bool {
+	for _, game := range m.Games {
+		if game.Estimate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule returns the planned and projected start of every game as of
+// now.  Skipped games take no time.
+func (m *Marathon) Schedule(now time.Time) []ScheduleEntry {
+	var entries []ScheduleEntry
+
+	var scheduled time.Time
+	if m.StartTime != nil {
+		scheduled = *m.StartTime
+	}
+	// Earliest time the next game can start.
+	cursor := now
+	if m.StartTime != nil && m.StartTime.After(now) {
+		cursor = *m.StartTime
+	}
+
+	for i, game := range m.Games {
+		entry := ScheduleEntry{
+			Number:    i + 1,
+			Game:      game,
+			Scheduled: scheduled,
+		}
+		if !scheduled.IsZero() {
+			scheduled = scheduled.Add(game.estimate() + m.setup(game))
+		}
+
+		status := game.status()
+		// Games without times can't be projected from.
+		if (status == GameStatusRunning && game.StartedTime == nil) ||
+			(status == GameStatusFinished && (game.StartedTime == nil || game.EndedTime == nil)) {
+			status = GameStatusSkipped
+		}
+
+		switch status {
+		case GameStatusFinished:
+			entry.Projected = *game.StartedTime
+			cursor = game.EndedTime.Add(m.setup(game))
+		case GameStatusRunning:
+			entry.Projected = *game.StartedTime
+			end := now
+			if left := game.estimate() - game.Elapsed(now); left > 0 {
+				end = now.Add(left)
+			}
+			cursor = end.Add(m.setup(game))
+		case GameStatusSkipped:
+			entry.Projected = cursor
+		default:
+			if cursor.Before(now) {
+				cursor = now
+			}
+			entry.Projected = cursor
+			cursor = cursor.Add(game.estimate() + m.setup(game))
+		}
+
+		if !entry.Scheduled.IsZero() {
+			entry.Delta = entry.Projected.Sub(entry.Scheduled)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// PrevGame goes back to the game before the running one, or to the last
+// finished game if none is running.
+func (m *Marathon) PrevGame() error {
+	cur := m.CurrentGameIndex()
+	last := cur
+	if last < 0 {
+		last = len(m.Games)
+	}
+
+	prev := -1
+	for i := last - 1; i >= 0; i-- {
+		if m.Games[i].status() == GameStatusFinished {
+			prev = i
+			break
+		}
+	}
+	if prev < 0 {
+		return fmt.Errorf("No previous game.")
+	}
+
+	if cur >= 0 {
+		m.Games[cur].reset()
+	}
+	m.Games[prev].setStatus(GameStatusRunning)
+	m.Games[prev].EndedTime = nil
+	return nil
+}
+
+// SkipGame marks the running game skipped and starts the next one.  If no
+// game is running the upcoming game is skipped.  It returns the skipped
+// game.
+func (m *Marathon) SkipGame(now time.Time) (*MarathonGame, error) {
+	game := m.CurrentGame()
+	if game == nil {
+		game = m.UpcomingGame()
+		if game == nil {
+			return nil, fmt.Errorf("No game to skip.")
+		}
+		game.setStatus(GameStatusSkipped)
+		return game, nil
+	}
+
+	// A skipped game has no times, so it can't stay paused either.
+	game.reset()
+	game.setStatus(GameStatusSkipped)
+	if next := m.UpcomingGame(); next != nil {
+		next.setStatus(GameStatusRunning)
+		next.StartedTime = &now
+	}
+	return game, nil
+}
+
+// GotoGame starts game n (counting from 0).  The running game is finished,
+// earlier games that never ran are skipped and later games are reset.
+func (m *Marathon) GotoGame(n int, now time.Time) error {
+	if n < 0 || n >= len(m.Games) {
+		return fmt.Errorf("No game %d.", n+1)
+	}
+
+	for i, game := range m.Games {
+		switch {
+		case i < n:
+			switch game.status() {
+			case GameStatusRunning:
+				game.finish(now)
+			case GameStatusNotStarted:
+				game.setStatus(GameStatusSkipped)
+			}
+		case i == n:
+			if game.status() != GameStatusRunning {
+				// Restarted games don't keep the last run's pauses or splits.
+				game.reset()
+				game.setStatus(GameStatusRunning)
+				t := now
+				game.StartedTime = &t
+			}
+		default:
+			game.reset()
 		}
 	}
 	return nil
@@ -255,11 +874,13 @@ func (m *Marathon) CurrentGame() *MarathonGame {
 	return nil
 }
 
-func (m *Marathon) NextGame() error {
+// NextGame finishes the running game and starts the upcoming one.
+func (m *Marathon) NextGame(now time.Time) error {
 	if len(m.Games) == 0 {
 		return fmt.Errorf("No games.")
 	}
 
+	finished := false
 	for _, game := range m.Games {
 		if game.Status == nil {
 			var status = GameStatusNotStarted
@@ -268,26 +889,24 @@ func (m *Marathon) NextGame() error {
 
 		if *game.Status == GameStatusNotStarted {
 			*game.Status = GameStatusRunning
-			t := time.Now()
-			game.StartedTime = &t
+			game.StartedTime = &now
 			return nil
 
 		} else if *game.Status == GameStatusRunning {
-			*game.Status = GameStatusFinished
-			t := time.Now()
-			game.EndedTime = &t
+			game.finish(now)
+			finished = true
 		}
 	}
+	if !finished {
+		return fmt.Errorf("No more games.")
+	}
 	return nil
 }
 
 func (m *Marathon) ResetGame() error {
 	for _, game := range m.Games {
-		if game.Status != nil && *game.Status == GameStatusRunning {
-			var status = GameStatusNotStarted
-			game.Status = &status
-			game.StartedTime = nil
-			game.EndedTime = nil
+		if game.status() == GameStatusRunning {
+			game.reset()
 		}
 	}
 	return nil
@@ -295,10 +914,7 @@ func (m *Marathon) ResetGame() error {
 
 func (m *Marathon) ResetMarathon() error {
 	for _, game := range m.Games {
-		var status = GameStatusNotStarted
-		game.Status = &status
-		game.StartedTime = nil
-		game.EndedTime = nil
+		game.reset()
 	}
 	return nil
 }
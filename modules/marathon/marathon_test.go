@@ -0,0 +1,190 @@
+package marathon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/konkers/roll"
+	"github.com/konkers/roll/clocktest"
+)
+
+func newTestMarathon(names ...string) *Marathon {
+	m := &Marathon{}
+	for i := range names {
+		m.Games = append(m.Games, &MarathonGame{Name: &names[i]})
+	}
+	return m
+}
+
+func TestMarathonTiming(t *testing.T) {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := newTestMarathon("a", "b", "c")
+
+	if err := m.NextGame(clock.Now()); err != nil {
+		t.Fatalf("NextGame() returned error: %v", err)
+	}
+	a := m.Games[0]
+	clock.Advance(10 * time.Minute)
+	if err := a.Pause(clock.Now()); err != nil {
+		t.Fatalf("Pause() returned error: %v", err)
+	}
+	if err := a.Pause(clock.Now()); err == nil {
+		t.Errorf("Pause() of a paused game didn't return an error")
+	}
+	clock.Advance(5 * time.Minute)
+	if d := a.Elapsed(clock.Now()); d != 10*time.Minute {
+		t.Errorf("Paused game elapsed %v instead of 10m", d)
+	}
+	if err := a.Resume(clock.Now()); err != nil {
+		t.Fatalf("Resume() returned error: %v", err)
+	}
+	clock.Advance(20 * time.Minute)
+	split, err := m.Split("", clock.Now())
+	if err != nil {
+		t.Fatalf("Split() returned error: %v", err)
+	}
+	if split.Name != "1" || split.Time.Duration != 30*time.Minute {
+		t.Errorf("Split() recorded %s at %v instead of 1 at 30m", split.Name, split.Time)
+	}
+
+	// Finishing a paused game counts the pause up to the finish.
+	clock.Advance(10 * time.Minute)
+	a.Pause(clock.Now())
+	clock.Advance(10 * time.Minute)
+	if err := m.NextGame(clock.Now()); err != nil {
+		t.Fatalf("NextGame() returned error: %v", err)
+	}
+	if a.status() != GameStatusFinished || a.Paused != nil {
+		t.Errorf("Finished game is %v, paused %v", a.status(), a.Paused)
+	}
+	clock.Advance(time.Hour)
+	if d := a.Elapsed(clock.Now()); d != 40*time.Minute {
+		t.Errorf("Finished game elapsed %v instead of 40m", d)
+	}
+	b := m.Games[1]
+	if b.status() != GameStatusRunning || !b.StartedTime.Equal(*a.EndedTime) {
+		t.Errorf("Next game is %v started at %v", b.status(), b.StartedTime)
+	}
+	if d := b.Elapsed(clock.Now()); d != time.Hour {
+		t.Errorf("Next game elapsed %v instead of 1h", d)
+	}
+
+	// Going back to a finished game starts it over.
+	if err := m.GotoGame(0, clock.Now()); err != nil {
+		t.Fatalf("GotoGame() returned error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if d := a.Elapsed(clock.Now()); d != time.Minute {
+		t.Errorf("Restarted game elapsed %v instead of 1m", d)
+	}
+	if a.PausedTotal != nil || len(a.Splits) != 0 {
+		t.Errorf("Restarted game kept pauses %v and splits %v", a.PausedTotal, a.Splits)
+	}
+	if b.status() != GameStatusNotStarted {
+		t.Errorf("Later game is %v instead of not started", b.status())
+	}
+
+	// Skipping a paused game doesn't leave it paused.
+	a.Pause(clock.Now())
+	if skipped, err := m.SkipGame(clock.Now()); err != nil || skipped != a {
+		t.Fatalf("SkipGame() returned %v, %v", skipped, err)
+	}
+	if a.status() != GameStatusSkipped || a.Paused != nil {
+		t.Errorf("Skipped game is %v, paused %v", a.status(), a.Paused)
+	}
+	if m.CurrentGame() != b {
+		t.Errorf("Skipping didn't start the next game")
+	}
+}
+
+func TestMarathonEnd(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := newTestMarathon().NextGame(now); err == nil {
+		t.Errorf("NextGame() without games didn't return an error")
+	}
+
+	m := newTestMarathon("a", "b")
+	// Skipping with nothing running skips the upcoming game.
+	skipped, err := m.SkipGame(now)
+	if err != nil || skipped != m.Games[0] || m.CurrentGame() != nil {
+		t.Errorf("SkipGame() before the start returned %v, %v", skipped, err)
+	}
+	if err := m.NextGame(now); err != nil || m.CurrentGame() != m.Games[1] {
+		t.Fatalf("NextGame() returned %v", err)
+	}
+	// Finishing the last game works once.
+	if err := m.NextGame(now); err != nil || m.CurrentGame() != nil {
+		t.Errorf("NextGame() on the last game returned %v", err)
+	}
+	if err := m.NextGame(now); err == nil {
+		t.Errorf("NextGame() after the last game didn't return an error")
+	}
+	if _, err := m.SkipGame(now); err == nil {
+		t.Errorf("SkipGame() after the last game didn't return an error")
+	}
+}
+
+func minutes(n int) *roll.Duration {
+	return &roll.Duration{Duration: time.Duration(n) * time.Minute}
+}
+
+func TestMarathonSchedule(t *testing.T) {
+	start := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(n int) time.Time { return start.Add(time.Duration(n) * time.Minute) }
+	clock := clocktest.New(at(95))
+
+	m := newTestMarathon("a", "b", "c", "d", "e")
+	m.StartTime = &start
+	m.Setup = minutes(5)
+	for i, estimate := range []int{60, 30, 45, 60, 20} {
+		m.Games[i].Estimate = minutes(estimate)
+	}
+	m.Games[1].Setup = minutes(10)
+
+	// a ran 10 minutes over, b was skipped and c has run for 20 minutes.
+	a, b, c := m.Games[0], m.Games[1], m.Games[2]
+	a.setStatus(GameStatusFinished)
+	aStart, aEnd := at(0), at(70)
+	a.StartedTime, a.EndedTime = &aStart, &aEnd
+	b.setStatus(GameStatusSkipped)
+	c.setStatus(GameStatusRunning)
+	cStart := at(75)
+	c.StartedTime = &cStart
+
+	expect := func(what string, projected ...int) {
+		scheduled := []int{0, 65, 105, 155, 220}
+		entries := m.Schedule(clock.Now())
+		if len(entries) != len(m.Games) {
+			t.Fatalf("%s: Schedule() returned %d entries", what, len(entries))
+		}
+		for i, e := range entries {
+			if e.Number != i+1 || e.Game != m.Games[i] {
+				t.Errorf("%s: entry %d is game %d, %s", what, i, e.Number, *e.Game.Name)
+			}
+			want := at(projected[i])
+			delta := want.Sub(at(scheduled[i]))
+			if !e.Scheduled.Equal(at(scheduled[i])) || !e.Projected.Equal(want) || e.Delta != delta {
+				t.Errorf("%s: %s scheduled %v, projected %v, delta %v instead of %v, %v, %v",
+					what, *e.Game.Name, e.Scheduled, e.Projected, e.Delta,
+					at(scheduled[i]), want, delta)
+			}
+		}
+	}
+
+	// The skipped game takes no time and c is projected to take its
+	// estimate.
+	expect("On time", 0, 75, 75, 125, 190)
+
+	// Once c runs over its estimate, the rest start after it ends.
+	clock.Advance(40 * time.Minute)
+	expect("Running over", 0, 75, 75, 140, 205)
+
+	// Without a start time there's nothing scheduled.
+	m.StartTime = nil
+	for _, e := range m.Schedule(clock.Now()) {
+		if !e.Scheduled.IsZero() || e.Delta != 0 {
+			t.Errorf("%s scheduled %v with delta %v without a start time",
+				*e.Game.Name, e.Scheduled, e.Delta)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package points
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/konkers/roll"
+)
+
+// ErrInsufficientPoints is returned when a debit would make a balance
+// negative.
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// ErrSelfTransfer is returned when a user tries to transfer points to
+// themselves.
+var ErrSelfTransfer = errors.New("can't transfer points to yourself")
+
+// Balance is a viewer's points in a channel.
+type Balance struct {
+	ID       int    `json:"id" storm:"id,increment"`
+	Channel  string `json:"channel" storm:"index"`
+	Username string `json:"username" storm:"index"`
+	Points   int    `json:"points"`
+	// Time the viewer has been present in chat.
+	WatchTime roll.Duration `json:"watch_time"`
+}
+
+type Settings struct {
+	// How often present viewers are awarded points.
+	Interval roll.Duration `json:"interval"`
+	// Points awarded each interval.
+	Amount int `json:"amount"`
+	// Subscribers get Amount times this.
+	SubscriberMultiplier int `json:"subscriber_multiplier"`
+}
+
+type viewer struct {
+	subscriber bool
+}
+
+type PointsModule struct {
+	bot *roll.Bot
+	db  storm.Node
+
+	service *PointsService
+	closeC  chan struct{}
+
+	// Serializes balance changes.
+	balanceMu sync.Mutex
+
+	// Viewers present in each channel.
+	presentMu sync.Mutex
+	present   map[string]map[string]*viewer
+}
+
+const (
+	settingsBucket = "settings"
+	settingsKey    = "settings"
+)
+
+var defaultSettings = Settings{
+	Interval:             roll.Duration{Duration: 5 * time.Minute},
+	Amount:               1,
+	SubscriberMultiplier: 2,
+}
+
+func init() {
+	roll.RegisterModuleFactory(NewPointsModule, "points")
+}
+
+func NewPointsModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
+	module := &PointsModule{
+		bot:     bot,
+		db:      dbBucket,
+		closeC:  make(chan struct{}),
+		present: make(map[string]map[string]*viewer),
+	}
+	module.service = NewPointsService(module)
+
+	module.migrateBalances()
+
+	bot.AddCommand("points", "Shows your points or those of <user>.", module.pointsCommand, 0)
+	bot.AddCommand("give", "Gives <user> <amount> of your points.", module.giveCommand, 0)
+	bot.AddCommand("addpoints", "Adds <amount> points to <user>.", module.addPointsCommand, roll.UserLevelModerator)
+
+	return module, nil
+}
+
+// migrateBalances moves balances from the bucket per channel they used to
+// be kept in.
+func (m *PointsModule) migrateBalances() {
+	for _, channel := range m.bot.Channels() {
+		var balances []Balance
+		err := m.db.From(channel).All(&balances)
+		if err != nil || len(balances) == 0 {
+			continue
+		}
+		for _, b := range balances {
+			b.ID = 0
+			b.Channel = channel
+			if err := m.db.Save(&b); err != nil {
+				log.Printf("Can't migrate points of %s in %s: %v", b.Username, channel, err)
+			}
+		}
+		m.db.From(channel).Drop(&Balance{})
+	}
+}
+
+func (m *PointsModule) Start() error {
+	go m.worker()
+	return nil
+}
+
+func (m *PointsModule) Stop() error {
+	close(m.closeC)
+	return nil
+}
+
+func (m *PointsModule) GetRPCService() interface{} {
+	return m.service
+}
+
+func (m *PointsModule) Settings() Settings {
+	settings := defaultSettings
+	err := m.db.Get(settingsBucket, settingsKey, &settings)
+	if err != nil && err != storm.ErrNotFound {
+		log.Printf("Can't get points settings: %v", err)
+	}
+	return settings
+}
+
+func (m *PointsModule) SetSettings(settings *Settings) error {
+	if settings.Interval.Duration <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	return m.db.Set(settingsBucket, settingsKey, settings)
+}
+
+func (m *PointsModule) HandleEvent(event *roll.Event) {
+	m.presentMu.Lock()
+	defer m.presentMu.Unlock()
+
+	viewers, ok := m.present[event.Channel]
+	if !ok {
+		viewers = make(map[string]*viewer)
+		m.present[event.Channel] = viewers
+	}
+	username := strings.ToLower(event.Username)
+
+	switch event.Type {
+	case roll.EventJoin:
+		if _, ok := viewers[username]; !ok {
+			viewers[username] = &viewer{}
+		}
+	case roll.EventPart:
+		delete(viewers, username)
+	case roll.EventMessage:
+		viewers[username] = &viewer{subscriber: roll.IsSubscriber(event.User)}
+	}
+}
+
+// award gives points to everyone present.
+func (m *PointsModule) award() {
+	settings := m.Settings()
+
+	m.presentMu.Lock()
+	awards := make(map[string]map[string]int)
+	for channel, viewers := range m.present {
+		if !m.bot.ModuleEnabled(channel, m) {
+			continue
+		}
+		awards[channel] = make(map[string]int)
+		for username, v := range viewers {
+			amount := settings.Amount
+			if v.subscriber {
+				amount *= settings.SubscriberMultiplier
+			}
+			awards[channel][username] = amount
+		}
+	}
+	m.presentMu.Unlock()
+
+	for channel, users := range awards {
+		for username, amount := range users {
+			_, err := m.credit(channel, username, amount, settings.Interval.Duration)
+			if err != nil {
+				log.Printf("Can't award points to %s in %s: %v", username, channel, err)
+			}
+		}
+	}
+}
+
+func (m *PointsModule) worker() {
+	interval := m.Settings().Interval.Duration
+	ticker := m.bot.Clock().NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			m.award()
+			// Pick up interval changes.
+			if i := m.Settings().Interval.Duration; i != interval {
+				interval = i
+				ticker.Stop()
+				ticker = m.bot.Clock().NewTicker(interval)
+			}
+		case <-m.closeC:
+			return
+		}
+	}
+}
+
+func (m *PointsModule) getBalance(channel string, username string) (*Balance, error) {
+	return findBalance(m.db, channel, username)
+}
+
+// findBalance returns username's balance in channel from node, or an empty
+// balance if there is none.
+func findBalance(node storm.Node, channel string, username string) (*Balance, error) {
+	b := &Balance{Channel: channel, Username: strings.ToLower(username)}
+	err := node.Select(q.Eq("Channel", channel), q.Eq("Username", b.Username)).First(b)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Balance returns username's points in channel.
+func (m *PointsModule) Balance(channel string, username string) (int, error) {
+	b, err := m.getBalance(channel, username)
+	if err != nil {
+		return 0, err
+	}
+	return b.Points, nil
+}
+
+// WatchTime returns how long username has been present in channel's chat.
+func (m *PointsModule) WatchTime(channel string, username string) (time.Duration, error) {
+	b, err := m.getBalance(channel, username)
+	if err != nil {
+		return 0, err
+	}
+	return b.WatchTime.Duration, nil
+}
+
+// Balances returns all balances in channel.
+func (m *PointsModule) Balances(channel string) ([]Balance, error) {
+	var balances []Balance
+	err := m.db.Find("Channel", channel, &balances)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return balances, err
+}
+
+// Credit adds amount to username's balance in channel and returns the new
+// balance.  A negative amount is a debit.
+func (m *PointsModule) Credit(channel string, username string, amount int) (int, error) {
+	return m.credit(channel, username, amount, 0)
+}
+
+// credit is Credit that also adds watched to username's watch time.
+func (m *PointsModule) credit(channel string, username string, amount int,
+	watched time.Duration) (int, error) {
+	m.balanceMu.Lock()
+	defer m.balanceMu.Unlock()
+
+	tx, err := m.db.Begin(true)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	points, err := adjust(tx, channel, username, amount, watched)
+	if err != nil {
+		return points, err
+	}
+	return points, tx.Commit()
+}
+
+// Debit removes amount from username's balance in channel and returns the
+// new balance.  ErrInsufficientPoints is returned, and nothing is changed,
+// if the balance is too low.
+func (m *PointsModule) Debit(channel string, username string, amount int) (int, error) {
+	return m.Credit(channel, username, -amount)
+}
+
+// Transfer moves amount points from one user to another.
+func (m *PointsModule) Transfer(channel string, from string, to string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if strings.EqualFold(from, to) {
+		return ErrSelfTransfer
+	}
+
+	m.balanceMu.Lock()
+	defer m.balanceMu.Unlock()
+
+	tx, err := m.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = adjust(tx, channel, from, -amount, 0)
+	if err != nil {
+		return err
+	}
+	_, err = adjust(tx, channel, to, amount, 0)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// adjust changes username's balance and watch time in channel within
+// transaction tx.
+func adjust(tx storm.Node, channel string, username string, amount int,
+	watched time.Duration) (int, error) {
+	b, err := findBalance(tx, channel, username)
+	if err != nil {
+		return 0, err
+	}
+
+	if b.Points+amount < 0 {
+		return b.Points, ErrInsufficientPoints
+	}
+	b.Points += amount
+	b.WatchTime.Duration += watched
+	return b.Points, tx.Save(b)
+}
+
+func (m *PointsModule) pointsCommand(cc *roll.CommandContext, args []string) error {
+	username := cc.User.Username
+	if len(args) > 0 {
+		username = strings.TrimPrefix(args[0], "@")
+	}
+
+	points, err := m.Balance(cc.Channel, username)
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("%s has %d points.", username, points))
+	return nil
+}
+
+// parseTransfer parses "<user> <amount>" arguments.
+func parseTransfer(args []string) (string, int, error) {
+	if len(args) != 2 {
+		return "", 0, fmt.Errorf("expected <user> <amount>")
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("%s is not a number", args[1])
+	}
+	return strings.TrimPrefix(args[0], "@"), amount, nil
+}
+
+func (m *PointsModule) giveCommand(cc *roll.CommandContext, args []string) error {
+	to, amount, err := parseTransfer(args)
+	if err != nil || amount <= 0 {
+		cc.Say(fmt.Sprintf("Usage: %sgive <user> <amount>", cc.Prefix))
+		return nil
+	}
+
+	err = m.Transfer(cc.Channel, cc.User.Username, to, amount)
+	if err == ErrInsufficientPoints {
+		cc.Say(fmt.Sprintf("%s, you don't have %d points.", cc.User.DisplayName, amount))
+		return nil
+	}
+	if err == ErrSelfTransfer {
+		cc.Say(fmt.Sprintf("%s, you can't give points to yourself.", cc.User.DisplayName))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("%s gave %d points to %s.", cc.User.DisplayName, amount, to))
+	return nil
+}
+
+func (m *PointsModule) addPointsCommand(cc *roll.CommandContext, args []string) error {
+	username, amount, err := parseTransfer(args)
+	if err != nil {
+		cc.Say(fmt.Sprintf("Usage: %saddpoints <user> <amount>", cc.Prefix))
+		return nil
+	}
+
+	points, err := m.Credit(cc.Channel, username, amount)
+	if err == ErrInsufficientPoints {
+		cc.Say(fmt.Sprintf("%s only has %d points.", username, points))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("%s now has %d points.", username, points))
+	return nil
+}
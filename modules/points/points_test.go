@@ -0,0 +1,170 @@
+package points
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm"
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/konkers/roll"
+	"github.com/konkers/roll/clocktest"
+	"github.com/konkers/roll/rolltest"
+)
+
+const channel = rolltest.Channel
+
+func newTestModule(t *testing.T) *PointsModule {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	bot := rolltest.NewBot(t, clock)
+	return rolltest.AddModule(t, bot, "points").(*PointsModule)
+}
+
+func (m *PointsModule) expectBalances(t *testing.T, what string, balances map[string]int) {
+	for username, want := range balances {
+		points, err := m.Balance(channel, username)
+		if err != nil {
+			t.Errorf("%s: Balance(%s) returned error: %v", what, username, err)
+		}
+		if points != want {
+			t.Errorf("%s: %s has %d points instead of %d", what, username, points, want)
+		}
+	}
+}
+
+// failingNode fails to save failUser's balance.
+type failingNode struct {
+	storm.Node
+	failUser string
+}
+
+func (n *failingNode) Begin(writable bool) (storm.Node, error) {
+	tx, err := n.Node.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+	return &failingNode{Node: tx, failUser: n.failUser}, nil
+}
+
+func (n *failingNode) Save(data interface{}) error {
+	if b, ok := data.(*Balance); ok && b.Username == n.failUser {
+		return errors.New("disk full")
+	}
+	return n.Node.Save(data)
+}
+
+func TestPointsDebit(t *testing.T) {
+	m := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	points, err := m.Credit(channel, "Alice", 10)
+	if err != nil || points != 10 {
+		t.Fatalf("Credit() returned %d, %v instead of 10, nil", points, err)
+	}
+	points, err = m.Debit(channel, "alice", 4)
+	if err != nil || points != 6 {
+		t.Errorf("Debit() returned %d, %v instead of 6, nil", points, err)
+	}
+
+	// An overdraft leaves the balance alone.
+	points, err = m.Debit(channel, "alice", 7)
+	if err != ErrInsufficientPoints || points != 6 {
+		t.Errorf("Overdraft returned %d, %v instead of 6, %v", points, err, ErrInsufficientPoints)
+	}
+	m.expectBalances(t, "After overdraft", map[string]int{"alice": 6})
+
+	// Balances are kept per channel.
+	points, err = m.Balance(rolltest.OtherChannel, "alice")
+	if err != nil || points != 0 {
+		t.Errorf("Balance in %s is %d, %v instead of 0, nil", rolltest.OtherChannel, points, err)
+	}
+}
+
+func TestPointsTransfer(t *testing.T) {
+	m := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	m.Credit(channel, "alice", 10)
+	m.Credit(channel, "bob", 1)
+
+	for _, test := range []struct {
+		from   string
+		to     string
+		amount int
+		err    error
+		alice  int
+		bob    int
+	}{
+		{"alice", "bob", 4, nil, 6, 5},
+		{"alice", "bob", 7, ErrInsufficientPoints, 6, 5},
+		{"alice", "Alice", 1, ErrSelfTransfer, 6, 5},
+		{"bob", "alice", 5, nil, 11, 0},
+	} {
+		err := m.Transfer(channel, test.from, test.to, test.amount)
+		if err != test.err {
+			t.Errorf("Transfer(%s, %s, %d) returned %v instead of %v",
+				test.from, test.to, test.amount, err, test.err)
+		}
+		m.expectBalances(t, "Transfer", map[string]int{"alice": test.alice, "bob": test.bob})
+	}
+
+	if err := m.Transfer(channel, "alice", "bob", 0); err == nil {
+		t.Errorf("Transfer of 0 points didn't return an error")
+	}
+}
+
+func TestPointsTransferRollback(t *testing.T) {
+	m := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	m.Credit(channel, "alice", 10)
+	m.db = &failingNode{Node: m.db, failUser: "bob"}
+
+	// The debit from alice is undone when the credit to bob fails.
+	if err := m.Transfer(channel, "alice", "bob", 4); err == nil {
+		t.Errorf("Transfer with a failing credit didn't return an error")
+	}
+	m.expectBalances(t, "After failed transfer", map[string]int{"alice": 10, "bob": 0})
+}
+
+func TestPointsAward(t *testing.T) {
+	m := newTestModule(t)
+	defer rolltest.Close(m.bot)
+
+	err := m.SetSettings(&Settings{
+		Interval:             roll.Duration{Duration: 10 * time.Minute},
+		Amount:               3,
+		SubscriberMultiplier: 4,
+	})
+	if err != nil {
+		t.Fatalf("SetSettings() returned error: %v", err)
+	}
+
+	for _, event := range []*roll.Event{
+		{Type: roll.EventJoin, Channel: channel, Username: "lurker"},
+		{Type: roll.EventMessage, Channel: channel, Username: "viewer",
+			User: &twitch.User{Username: "viewer"}},
+		{Type: roll.EventMessage, Channel: channel, Username: "sub",
+			User: &twitch.User{Username: "sub", Badges: map[string]int{"subscriber": 3}}},
+		{Type: roll.EventMessage, Channel: channel, Username: "founder",
+			User: &twitch.User{Username: "founder", Badges: map[string]int{"founder": 0}}},
+		{Type: roll.EventJoin, Channel: channel, Username: "leaver"},
+		{Type: roll.EventPart, Channel: channel, Username: "leaver"},
+	} {
+		m.HandleEvent(event)
+	}
+
+	m.award()
+	m.award()
+	m.expectBalances(t, "After two awards", map[string]int{
+		"lurker":  6,
+		"viewer":  6,
+		"sub":     24,
+		"founder": 24,
+		"leaver":  0,
+	})
+	watched, err := m.WatchTime(channel, "lurker")
+	if err != nil || watched != 20*time.Minute {
+		t.Errorf("WatchTime() returned %v, %v instead of 20m, nil", watched, err)
+	}
+}
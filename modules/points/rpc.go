@@ -0,0 +1,67 @@
+package points
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type PointsService struct {
+	module *PointsModule
+}
+
+// BalanceArgs identifies a viewer's balance.  Amount is used by Credit.
+type BalanceArgs struct {
+	Channel  string `json:"channel"`
+	Username string `json:"username"`
+	Amount   int    `json:"amount"`
+}
+
+type BalanceList struct {
+	Balances []Balance `json:"balances"`
+}
+
+func NewPointsService(module *PointsModule) *PointsService {
+	return &PointsService{
+		module: module,
+	}
+}
+
+func (s *PointsService) channel(channel string) string {
+	if channel == "" {
+		return s.module.bot.DefaultChannel()
+	}
+	return channel
+}
+
+func (s *PointsService) Get(r *http.Request, args *BalanceArgs, points *int) error {
+	var err error
+	*points, err = s.module.Balance(s.channel(args.Channel), args.Username)
+	return err
+}
+
+func (s *PointsService) Credit(r *http.Request, args *BalanceArgs, points *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	var err error
+	*points, err = s.module.Credit(s.channel(args.Channel), args.Username, args.Amount)
+	return err
+}
+
+func (s *PointsService) All(r *http.Request, channel *string, list *BalanceList) error {
+	var err error
+	list.Balances, err = s.module.Balances(s.channel(*channel))
+	return err
+}
+
+func (s *PointsService) GetSettings(r *http.Request, id *int, settings *Settings) error {
+	*settings = s.module.Settings()
+	return nil
+}
+
+func (s *PointsService) SetSettings(r *http.Request, settings *Settings, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	return s.module.SetSettings(settings)
+}
@@ -0,0 +1,416 @@
+package poll
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/konkers/roll"
+)
+
+const defaultDuration = 2 * time.Minute
+
+type Poll struct {
+	ID       int      `json:"id" storm:"id,increment"`
+	Channel  string   `json:"channel" storm:"index"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// Votes maps usernames to the index of the option they voted for.
+	Votes   map[string]int `json:"votes"`
+	Started time.Time      `json:"started"`
+	Ends    time.Time      `json:"ends"`
+	Open    bool           `json:"open"`
+}
+
+// Result is the tally for one option.
+type Result struct {
+	Option  string `json:"option"`
+	Votes   int    `json:"votes"`
+	Percent int    `json:"percent"`
+}
+
+// Results tallies the votes for each option.
+func (p *Poll) Results() []Result {
+	results := make([]Result, len(p.Options))
+	for i, o := range p.Options {
+		results[i].Option = o
+	}
+	for _, v := range p.Votes {
+		if v >= 0 && v < len(results) {
+			results[v].Votes++
+		}
+	}
+	if len(p.Votes) > 0 {
+		for i := range results {
+			results[i].Percent = results[i].Votes * 100 / len(p.Votes)
+		}
+	}
+	return results
+}
+
+type PollModule struct {
+	bot *roll.Bot
+	db  storm.Node
+
+	pollCmd *roll.CmdEngine
+	service *PollService
+
+	mu     sync.Mutex
+	timers map[string]roll.Timer
+}
+
+func init() {
+	roll.RegisterModuleFactory(NewPollModule, "poll")
+}
+
+func NewPollModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
+	module := &PollModule{
+		bot:     bot,
+		db:      dbBucket,
+		pollCmd: roll.NewCmdEngine(),
+		timers:  make(map[string]roll.Timer),
+	}
+	module.service = NewPollService(module)
+
+	module.pollCmd.AddCommand("start", "start a poll: [duration] \"question\" a | b | c", module.pollStartCommand, roll.UserLevelModerator)
+	module.pollCmd.AddCommand("end", "end the poll now", module.pollEndCommand, roll.UserLevelModerator)
+
+	err := bot.AddCommand("poll", "Shows the current poll.", module.pollCommand, 0)
+	if err != nil {
+		return nil, err
+	}
+	bot.AddSubEngine("poll", module.pollCmd)
+
+	err = bot.AddCommand("vote", "Votes for option <n> in the current poll.", module.voteCommand, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return module, nil
+}
+
+func (m *PollModule) Start() error {
+	// Pick up polls that were open when the bot stopped.
+	var polls []Poll
+	err := m.db.Find("Open", true, &polls)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range polls {
+		m.scheduleEnd(p.Channel, p.Ends.Sub(m.bot.Clock().Now()))
+	}
+	return nil
+}
+
+func (m *PollModule) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for channel, t := range m.timers {
+		t.Stop()
+		delete(m.timers, channel)
+	}
+	return nil
+}
+
+func (m *PollModule) GetRPCService() interface{} {
+	return m.service
+}
+
+func (m *PollModule) GetPublicHandler() http.Handler {
+	return http.HandlerFunc(m.pollHandler)
+}
+
+// scheduleEnd ends channel's poll after d.  m.mu must be held.
+func (m *PollModule) scheduleEnd(channel string, d time.Duration) {
+	if t, ok := m.timers[channel]; ok {
+		t.Stop()
+	}
+	m.timers[channel] = m.bot.Clock().AfterFunc(d, func() {
+		_, err := m.EndPoll(channel)
+		if err != nil && err != storm.ErrNotFound {
+			log.Printf("Can't end poll in %s: %v", channel, err)
+		}
+	})
+}
+
+// CurrentPoll returns the open poll in channel.
+func (m *PollModule) CurrentPoll(channel string) (*Poll, error) {
+	var polls []Poll
+	err := m.db.Find("Channel", channel, &polls)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range polls {
+		if p.Open {
+			return &p, nil
+		}
+	}
+	return nil, storm.ErrNotFound
+}
+
+// LatestPoll returns the open poll in channel or, if there is none, the
+// one that ended last.
+func (m *PollModule) LatestPoll(channel string) (*Poll, error) {
+	var polls []Poll
+	err := m.db.Find("Channel", channel, &polls)
+	if err != nil {
+		return nil, err
+	}
+	latest := &polls[0]
+	for i := range polls {
+		if polls[i].Open {
+			return &polls[i], nil
+		}
+		if polls[i].Ends.After(latest.Ends) {
+			latest = &polls[i]
+		}
+	}
+	return latest, nil
+}
+
+// StartPoll opens a poll in channel that ends after duration.
+func (m *PollModule) StartPoll(channel string, question string, options []string,
+	duration time.Duration) (*Poll, error) {
+	if len(options) < 2 {
+		return nil, fmt.Errorf("a poll needs at least two options")
+	}
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.CurrentPoll(channel); err == nil {
+		return nil, fmt.Errorf("a poll is already running")
+	}
+
+	now := m.bot.Clock().Now()
+	poll := &Poll{
+		Channel:  channel,
+		Question: question,
+		Options:  options,
+		Votes:    make(map[string]int),
+		Started:  now,
+		Ends:     now.Add(duration),
+		Open:     true,
+	}
+	err := m.db.Save(poll)
+	if err != nil {
+		return nil, err
+	}
+	m.scheduleEnd(channel, duration)
+	return poll, nil
+}
+
+// EndPoll closes channel's poll and announces the results.
+func (m *PollModule) EndPoll(channel string) (*Poll, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	poll, err := m.CurrentPoll(channel)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := m.timers[channel]; ok {
+		t.Stop()
+		delete(m.timers, channel)
+	}
+
+	poll.Open = false
+	poll.Ends = m.bot.Clock().Now()
+	err = m.db.Save(poll)
+	if err != nil {
+		return nil, err
+	}
+
+	m.bot.SayPriority(channel, formatResults(poll), roll.PriorityHigh)
+	return poll, nil
+}
+
+// Vote records username's vote for option (counting from 0) in channel's
+// poll.  Voting again changes the vote.
+func (m *PollModule) Vote(channel string, username string, option int) (*Poll, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	poll, err := m.CurrentPoll(channel)
+	if err != nil {
+		return nil, err
+	}
+	if option < 0 || option >= len(poll.Options) {
+		return nil, fmt.Errorf("no option %d", option+1)
+	}
+	if poll.Votes == nil {
+		poll.Votes = make(map[string]int)
+	}
+	poll.Votes[strings.ToLower(username)] = option
+	return poll, m.db.Save(poll)
+}
+
+func formatOptions(p *Poll) string {
+	var opts []string
+	for i, o := range p.Options {
+		opts = append(opts, fmt.Sprintf("%d) %s", i+1, o))
+	}
+	return strings.Join(opts, " ")
+}
+
+func formatResults(p *Poll) string {
+	if len(p.Votes) == 0 {
+		return fmt.Sprintf("Poll \"%s\" ended with no votes.", p.Question)
+	}
+
+	results := p.Results()
+	var res []string
+	most := 0
+	for _, r := range results {
+		res = append(res, fmt.Sprintf("%s: %d (%d%%)", r.Option, r.Votes, r.Percent))
+		if r.Votes > most {
+			most = r.Votes
+		}
+	}
+	var winners []string
+	for _, r := range results {
+		if r.Votes == most {
+			winners = append(winners, r.Option)
+		}
+	}
+	if len(winners) > 1 {
+		return fmt.Sprintf("Poll \"%s\" ended! %s. Tie between %s",
+			p.Question, strings.Join(res, ", "), strings.Join(winners, " and "))
+	}
+	return fmt.Sprintf("Poll \"%s\" ended! %s. Winner: %s",
+		p.Question, strings.Join(res, ", "), winners[0])
+}
+
+// parsePoll parses `[duration] "question" a | b | c`.
+func parsePoll(args []string) (string, []string, time.Duration, error) {
+	duration := defaultDuration
+	if len(args) > 0 {
+		if d, err := time.ParseDuration(args[0]); err == nil {
+			if d <= 0 {
+				return "", nil, 0, fmt.Errorf("duration must be positive")
+			}
+			duration = d
+			args = args[1:]
+		}
+	}
+
+	line := strings.TrimSpace(strings.Join(args, " "))
+	if !strings.HasPrefix(line, "\"") {
+		return "", nil, 0, fmt.Errorf("question must be quoted")
+	}
+	end := strings.Index(line[1:], "\"")
+	if end < 0 {
+		return "", nil, 0, fmt.Errorf("question must be quoted")
+	}
+	question := strings.TrimSpace(line[1 : end+1])
+	if question == "" {
+		return "", nil, 0, fmt.Errorf("question can't be empty")
+	}
+
+	var options []string
+	for _, o := range strings.Split(line[end+2:], "|") {
+		o = strings.TrimSpace(o)
+		if o != "" {
+			options = append(options, o)
+		}
+	}
+	if len(options) < 2 {
+		return "", nil, 0, fmt.Errorf("a poll needs at least two options")
+	}
+	return question, options, duration, nil
+}
+
+func (m *PollModule) pollCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) > 0 {
+		return m.pollCmd.Exec(cc, cc.UserLevel, args)
+	}
+
+	poll, err := m.CurrentPoll(cc.Channel)
+	if err == storm.ErrNotFound {
+		cc.Say("There's no poll running.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("%s %s (vote with %svote <n>)", poll.Question, formatOptions(poll), cc.Prefix))
+	return nil
+}
+
+func (m *PollModule) pollStartCommand(cc *roll.CommandContext, args []string) error {
+	question, options, duration, err := parsePoll(args)
+	if err != nil {
+		cc.Say(fmt.Sprintf("Usage: %spoll start [duration] \"question\" a | b | c (%v)", cc.Prefix, err))
+		return nil
+	}
+
+	poll, err := m.StartPoll(cc.Channel, question, options, duration)
+	if err != nil {
+		cc.Say(fmt.Sprintf("Can't start poll: %v", err))
+		return nil
+	}
+	cc.Say(fmt.Sprintf("Poll started: %s %s. Vote with %svote <n> in the next %s!",
+		poll.Question, formatOptions(poll), cc.Prefix, duration))
+	return nil
+}
+
+func (m *PollModule) pollEndCommand(cc *roll.CommandContext, args []string) error {
+	_, err := m.EndPoll(cc.Channel)
+	if err == storm.ErrNotFound {
+		cc.Say("There's no poll running.")
+		return nil
+	}
+	return err
+}
+
+func (m *PollModule) voteCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) != 1 {
+		cc.Say(fmt.Sprintf("Usage: %svote <n>", cc.Prefix))
+		return nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s is not an option number.", args[0]))
+		return nil
+	}
+
+	_, err = m.Vote(cc.Channel, cc.User.Username, n-1)
+	if err == storm.ErrNotFound {
+		cc.Say("There's no poll running.")
+		return nil
+	}
+	if err != nil {
+		cc.Whisper(err.Error())
+	}
+	return nil
+}
+
+func (m *PollModule) pollHandler(w http.ResponseWriter, req *http.Request) {
+	channel := req.FormValue("channel")
+	if channel == "" {
+		channel = m.bot.DefaultChannel()
+	}
+
+	poll, err := m.LatestPoll(strings.ToLower(channel))
+	if err != nil && err != storm.ErrNotFound {
+		log.Printf("Can't get poll: %v", err)
+		http.Error(w, "Can't get poll", http.StatusInternalServerError)
+		return
+	}
+
+	err = m.bot.ExecuteTemplate(w, "poll.html", poll)
+	if err != nil {
+		log.Printf("Can't render poll: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package poll
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/konkers/roll/clocktest"
+	"github.com/konkers/roll/rolltest"
+)
+
+func TestParsePoll(t *testing.T) {
+	tests := []struct {
+		line     string
+		question string
+		options  []string
+		duration time.Duration
+		ok       bool
+	}{
+		{`"Best game?" a | b | c`, "Best game?", []string{"a", "b", "c"}, defaultDuration, true},
+		{`5m "Best game?" a | b`, "Best game?", []string{"a", "b"}, 5 * time.Minute, true},
+		{`"Spaced out" one thing |  another thing `, "Spaced out", []string{"one thing", "another thing"}, defaultDuration, true},
+		{`"Empties?" a | | b |`, "Empties?", []string{"a", "b"}, defaultDuration, true},
+		{`"Question?"a|b`, "Question?", []string{"a", "b"}, defaultDuration, true},
+		{`Unquoted? a | b`, "", nil, 0, false},
+		{`"Unclosed? a | b`, "", nil, 0, false},
+		{`"" a | b`, "", nil, 0, false},
+		{`"One option?" a`, "", nil, 0, false},
+		{`"Only empties?" | |`, "", nil, 0, false},
+		{`0s "Right now?" a | b`, "", nil, 0, false},
+		{`-5m "Backwards?" a | b`, "", nil, 0, false},
+		{`5m`, "", nil, 0, false},
+		{``, "", nil, 0, false},
+	}
+
+	for _, test := range tests {
+		question, options, duration, err := parsePoll(strings.Fields(test.line))
+		if test.ok != (err == nil) {
+			t.Errorf("parsePoll(%s) returned error %v", test.line, err)
+			continue
+		}
+		if question != test.question || !reflect.DeepEqual(options, test.options) ||
+			duration != test.duration {
+			t.Errorf("parsePoll(%s) returned %q, %q, %v instead of %q, %q, %v", test.line,
+				question, options, duration, test.question, test.options, test.duration)
+		}
+	}
+}
+
+func TestPollResults(t *testing.T) {
+	tests := []struct {
+		votes   map[string]int
+		results []Result
+		message string
+	}{
+		{
+			map[string]int{},
+			[]Result{{"a", 0, 0}, {"b", 0, 0}, {"c", 0, 0}},
+			`Poll "q" ended with no votes.`,
+		},
+		{
+			map[string]int{"alice": 1, "bob": 1, "carol": 0, "dave": 7},
+			[]Result{{"a", 1, 25}, {"b", 2, 50}, {"c", 0, 0}},
+			`Poll "q" ended! a: 1 (25%), b: 2 (50%), c: 0 (0%). Winner: b`,
+		},
+		{
+			map[string]int{"alice": 0, "bob": 2},
+			[]Result{{"a", 1, 50}, {"b", 0, 0}, {"c", 1, 50}},
+			`Poll "q" ended! a: 1 (50%), b: 0 (0%), c: 1 (50%). Tie between a and c`,
+		},
+	}
+
+	for _, test := range tests {
+		p := &Poll{Question: "q", Options: []string{"a", "b", "c"}, Votes: test.votes}
+		if results := p.Results(); !reflect.DeepEqual(results, test.results) {
+			t.Errorf("Results() of %v returned %v instead of %v", test.votes, results, test.results)
+		}
+		if msg := formatResults(p); msg != test.message {
+			t.Errorf("formatResults() of %v returned %q instead of %q", test.votes, msg, test.message)
+		}
+	}
+}
+
+func TestPollServiceHidesVotes(t *testing.T) {
+	clock := clocktest.New(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	bot := rolltest.NewBot(t, clock)
+	defer rolltest.Close(bot)
+	m := rolltest.AddModule(t, bot, "poll").(*PollModule)
+
+	poll, err := m.StartPoll(rolltest.Channel, "q", []string{"a", "b"}, time.Minute)
+	if err != nil {
+		t.Fatalf("StartPoll() returned error: %v", err)
+	}
+	_, err = m.Vote(rolltest.Channel, "alice", 1)
+	if err != nil {
+		t.Fatalf("Vote() returned error: %v", err)
+	}
+
+	public := httptest.NewRequest("POST", "/rpc", nil)
+	var results PollResults
+	err = m.service.Get(public, &poll.ID, &results)
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if results.Poll.Votes != nil || results.Results[1].Votes != 1 {
+		t.Errorf("Public Get() returned votes %v and results %v", results.Poll.Votes, results.Results)
+	}
+
+	channel := rolltest.Channel
+	var list PollList
+	err = m.service.All(public, &channel, &list)
+	if err != nil {
+		t.Fatalf("All() returned error: %v", err)
+	}
+	if len(list.Polls) != 1 || list.Polls[0].Poll.Votes != nil {
+		t.Errorf("Public All() returned %v", list.Polls)
+	}
+
+	// Internal requests are admin requests.
+	err = m.service.Latest(nil, &channel, &results)
+	if err != nil {
+		t.Fatalf("Latest() returned error: %v", err)
+	}
+	if results.Poll.Votes["alice"] != 1 {
+		t.Errorf("Admin Latest() returned votes %v", results.Poll.Votes)
+	}
+}
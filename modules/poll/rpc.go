@@ -0,0 +1,116 @@
+package poll
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/asdine/storm"
+	"github.com/konkers/roll"
+)
+
+type PollService struct {
+	module *PollModule
+}
+
+type StartArgs struct {
+	Channel  string        `json:"channel"`
+	Question string        `json:"question"`
+	Options  []string      `json:"options"`
+	Duration roll.Duration `json:"duration"`
+}
+
+type PollList struct {
+	Polls []PollResults `json:"polls"`
+}
+
+// PollResults is a poll along with its tally.
+type PollResults struct {
+	Poll    Poll     `json:"poll"`
+	Results []Result `json:"results"`
+}
+
+func NewPollService(module *PollModule) *PollService {
+	return &PollService{
+		module: module,
+	}
+}
+
+func (s *PollService) channel(channel string) string {
+	if channel == "" {
+		return s.module.bot.DefaultChannel()
+	}
+	return channel
+}
+
+func (s *PollService) Start(r *http.Request, args *StartArgs, id *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	poll, err := s.module.StartPoll(s.channel(args.Channel), args.Question, args.Options, args.Duration.Duration)
+	if err != nil {
+		*id = -1
+		return err
+	}
+	*id = poll.ID
+	return nil
+}
+
+func (s *PollService) End(r *http.Request, channel *string, id *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	poll, err := s.module.EndPoll(s.channel(*channel))
+	if err != nil {
+		*id = -1
+		return err
+	}
+	*id = poll.ID
+	return nil
+}
+
+// pollResults tallies poll.  Who voted for what is only given to admins.
+func (s *PollService) pollResults(r *http.Request, poll *Poll) PollResults {
+	results := PollResults{
+		Poll:    *poll,
+		Results: poll.Results(),
+	}
+	if !s.module.bot.IsAdminRequest(r) {
+		results.Poll.Votes = nil
+	}
+	return results
+}
+
+func (s *PollService) Get(r *http.Request, id *int, results *PollResults) error {
+	var poll Poll
+	err := s.module.db.One("ID", *id, &poll)
+	if err != nil {
+		return err
+	}
+	*results = s.pollResults(r, &poll)
+	return nil
+}
+
+// Latest returns the running poll in a channel, or the last one to end.
+func (s *PollService) Latest(r *http.Request, channel *string, results *PollResults) error {
+	poll, err := s.module.LatestPoll(s.channel(*channel))
+	if err != nil {
+		return err
+	}
+	*results = s.pollResults(r, poll)
+	return nil
+}
+
+func (s *PollService) All(r *http.Request, channel *string, list *PollList) error {
+	var polls []Poll
+	err := s.module.db.Find("Channel", s.channel(*channel), &polls)
+	if err == storm.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i := range polls {
+		list.Polls = append(list.Polls, s.pollResults(r, &polls[i]))
+	}
+	return nil
+}
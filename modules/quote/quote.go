@@ -0,0 +1,291 @@
+package quote
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/konkers/roll"
+)
+
+type Quote struct {
+	ID      int    `json:"id" storm:"id,increment"`
+	Channel string `json:"channel" storm:"index"`
+	// Numbers count up from 1 in each channel.
+	Number  int       `json:"number" storm:"index"`
+	Text    string    `json:"text"`
+	Game    string    `json:"game"`
+	AddedBy string    `json:"added_by"`
+	Time    time.Time `json:"time"`
+}
+
+type QuoteModule struct {
+	bot *roll.Bot
+	db  storm.Node
+
+	quoteCmd *roll.CmdEngine
+	service  *QuoteService
+
+	// Serializes number assignment.
+	mu sync.Mutex
+}
+
+func init() {
+	roll.RegisterModuleFactory(NewQuoteModule, "quote")
+}
+
+func NewQuoteModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
+	module := &QuoteModule{
+		bot:      bot,
+		db:       dbBucket,
+		quoteCmd: roll.NewCmdEngine(),
+	}
+	module.service = NewQuoteService(module)
+
+	module.quoteCmd.AddCommand("add", "add a quote", module.quoteAddCommand, roll.UserLevelModerator)
+	module.quoteCmd.AddCommand("del", "delete a quote", module.quoteDelCommand, roll.UserLevelModerator)
+	module.quoteCmd.AddCommand("search", "find a quote containing a word", module.quoteSearchCommand, 0)
+
+	err := bot.AddCommand("quote", "Shows a random quote or quote number <n>.", module.quoteCommand, 0)
+	if err != nil {
+		return nil, err
+	}
+	bot.AddSubEngine("quote", module.quoteCmd)
+
+	return module, nil
+}
+
+func (m *QuoteModule) Start() error {
+	return nil
+}
+
+func (m *QuoteModule) Stop() error {
+	return nil
+}
+
+func (m *QuoteModule) GetRPCService() interface{} {
+	return m.service
+}
+
+func (m *QuoteModule) GetPublicHandler() http.Handler {
+	return http.HandlerFunc(m.quotesHandler)
+}
+
+func (m *QuoteModule) channelQuotes(channel string) ([]Quote, error) {
+	var quotes []Quote
+	err := m.db.Find("Channel", channel, &quotes)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	return quotes, err
+}
+
+func (m *QuoteModule) getQuote(channel string, number int) (*Quote, error) {
+	quotes, err := m.channelQuotes(channel)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range quotes {
+		if q.Number == number {
+			return &q, nil
+		}
+	}
+	return nil, storm.ErrNotFound
+}
+
+// AddQuote saves a new quote for channel and returns it.
+func (m *QuoteModule) AddQuote(channel string, text string, game string, addedBy string) (*Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quotes, err := m.channelQuotes(channel)
+	if err != nil {
+		return nil, err
+	}
+	number := 1
+	for _, q := range quotes {
+		if q.Number >= number {
+			number = q.Number + 1
+		}
+	}
+
+	quote := &Quote{
+		Channel: channel,
+		Number:  number,
+		Text:    text,
+		Game:    game,
+		AddedBy: addedBy,
+		Time:    m.bot.Clock().Now(),
+	}
+	err = m.db.Save(quote)
+	if err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// UpdateQuote changes the text and game of quote id.  Its number, channel
+// and time are kept.
+func (m *QuoteModule) UpdateQuote(id int, text string, game string) (*Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var quote Quote
+	err := m.db.One("ID", id, &quote)
+	if err != nil {
+		return nil, err
+	}
+	quote.Text = text
+	quote.Game = game
+	err = m.db.Save(&quote)
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func formatQuote(q *Quote) string {
+	s := fmt.Sprintf("#%d: \"%s\"", q.Number, q.Text)
+	if q.Game != "" {
+		s += fmt.Sprintf(" [%s, %s]", q.Game, q.Time.Format("2006-01-02"))
+	} else {
+		s += fmt.Sprintf(" [%s]", q.Time.Format("2006-01-02"))
+	}
+	return s
+}
+
+func (m *QuoteModule) quoteCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) == 0 {
+		quotes, err := m.channelQuotes(cc.Channel)
+		if err != nil {
+			return err
+		}
+		if len(quotes) == 0 {
+			cc.Say(fmt.Sprintf("There are no quotes yet.  Moderators can add one with %squote add <text>.",
+				cc.Prefix))
+			return nil
+		}
+		cc.Say(formatQuote(&quotes[rand.Intn(len(quotes))]))
+		return nil
+	}
+
+	if number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#")); err == nil {
+		quote, err := m.getQuote(cc.Channel, number)
+		if err == storm.ErrNotFound {
+			cc.Say(fmt.Sprintf("There's no quote #%d.", number))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cc.Say(formatQuote(quote))
+		return nil
+	}
+
+	if _, ok := m.quoteCmd.Lookup(args[:1]); ok {
+		return m.quoteCmd.Exec(cc, cc.UserLevel, args)
+	}
+
+	return m.quoteSearchCommand(cc, args)
+}
+
+func (m *QuoteModule) quoteAddCommand(cc *roll.CommandContext, args []string) error {
+	text := strings.Join(args, " ")
+	if text == "" {
+		cc.Say(fmt.Sprintf("Usage: %squote add <text>", cc.Prefix))
+		return nil
+	}
+
+	game, err := cc.Bot.ChannelGame(cc.Channel)
+	if err != nil {
+		log.Printf("Can't get current game of %s for quote: %v", cc.Channel, err)
+	}
+
+	quote, err := m.AddQuote(cc.Channel, text, game, cc.User.Username)
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("Added quote #%d.", quote.Number))
+	return nil
+}
+
+func (m *QuoteModule) quoteDelCommand(cc *roll.CommandContext, args []string) error {
+	if len(args) != 1 {
+		cc.Say(fmt.Sprintf("Usage: %squote del <number>", cc.Prefix))
+		return nil
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		cc.Say(fmt.Sprintf("%s is not a quote number.", args[0]))
+		return nil
+	}
+
+	quote, err := m.getQuote(cc.Channel, number)
+	if err == storm.ErrNotFound {
+		cc.Say(fmt.Sprintf("There's no quote #%d.", number))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = m.db.DeleteStruct(quote)
+	if err != nil {
+		return err
+	}
+	cc.Say(fmt.Sprintf("Deleted quote #%d.", number))
+	return nil
+}
+
+func (m *QuoteModule) quoteSearchCommand(cc *roll.CommandContext, args []string) error {
+	keyword := strings.ToLower(strings.Join(args, " "))
+	if keyword == "" {
+		cc.Say(fmt.Sprintf("Usage: %squote search <word>", cc.Prefix))
+		return nil
+	}
+
+	quotes, err := m.channelQuotes(cc.Channel)
+	if err != nil {
+		return err
+	}
+
+	var matches []Quote
+	for _, q := range quotes {
+		if strings.Contains(strings.ToLower(q.Text), keyword) ||
+			strings.Contains(strings.ToLower(q.Game), keyword) {
+			matches = append(matches, q)
+		}
+	}
+
+	if len(matches) == 0 {
+		cc.Say(fmt.Sprintf("No quotes match \"%s\".", keyword))
+		return nil
+	}
+	cc.Say(formatQuote(&matches[rand.Intn(len(matches))]))
+	return nil
+}
+
+func (m *QuoteModule) quotesHandler(w http.ResponseWriter, req *http.Request) {
+	channel := req.FormValue("channel")
+	if channel == "" {
+		channel = m.bot.DefaultChannel()
+	}
+
+	quotes, err := m.channelQuotes(strings.ToLower(channel))
+	if err != nil {
+		log.Printf("Can't get quotes: %v", err)
+		http.Error(w, "Can't get quotes", http.StatusInternalServerError)
+		return
+	}
+
+	err = m.bot.ExecuteTemplate(w, "quotes.html", quotes)
+	if err != nil {
+		log.Printf("Can't render quotes: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package quote
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type QuoteService struct {
+	module *QuoteModule
+}
+
+type QuoteList struct {
+	Quotes []Quote `json:"quotes"`
+}
+
+func NewQuoteService(module *QuoteModule) *QuoteService {
+	return &QuoteService{
+		module: module,
+	}
+}
+
+func (s *QuoteService) channel(channel string) string {
+	if channel == "" {
+		return s.module.bot.DefaultChannel()
+	}
+	return channel
+}
+
+func (s *QuoteService) New(r *http.Request, q *Quote, id *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	if q.Channel == "" {
+		q.Channel = s.module.bot.DefaultChannel()
+	}
+	quote, err := s.module.AddQuote(q.Channel, q.Text, q.Game, q.AddedBy)
+	if err != nil {
+		*id = -1
+		return err
+	}
+	*id = quote.ID
+	return nil
+}
+
+func (s *QuoteService) Update(r *http.Request, q *Quote, id *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	if q.ID == 0 {
+		return fmt.Errorf("no quote id given")
+	}
+	quote, err := s.module.UpdateQuote(q.ID, q.Text, q.Game)
+	if err != nil {
+		*id = -1
+		return err
+	}
+
+	*id = quote.ID
+	return nil
+}
+
+func (s *QuoteService) Get(r *http.Request, id *int, q *Quote) error {
+	return s.module.db.One("ID", *id, q)
+}
+
+func (s *QuoteService) Del(r *http.Request, id *int, ret *int) error {
+	if !s.module.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	var q Quote
+	err := s.module.db.One("ID", *id, &q)
+	if err != nil {
+		return err
+	}
+
+	err = s.module.db.DeleteStruct(&q)
+	if err != nil {
+		return err
+	}
+	*ret = *id
+	return nil
+}
+
+func (s *QuoteService) All(r *http.Request, channel *string, list *QuoteList) error {
+	var err error
+	list.Quotes, err = s.module.channelQuotes(s.channel(*channel))
+	return err
+}
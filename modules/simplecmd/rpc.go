@@ -1,8 +1,10 @@
-package giveaway
+package simplecmd
 
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/asdine/storm"
 )
 
 type SimpleCommandService struct {
@@ -10,7 +12,7 @@ type SimpleCommandService struct {
 }
 
 type SimpleCommandList struct {
-	Commands []SimpleCommand `json :"commands"`
+	Commands []SimpleCommand `json:"commands"`
 }
 
 func NewSimpleCommandService(module *SimpleCommandModule) *SimpleCommandService {
@@ -19,6 +21,13 @@ func NewSimpleCommandService(module *SimpleCommandModule) *SimpleCommandService
 	}
 }
 
+func (s *SimpleCommandService) channel(channel string) string {
+	if channel == "" {
+		return s.module.bot.DefaultChannel()
+	}
+	return channel
+}
+
 func (s *SimpleCommandService) New(r *http.Request, g *SimpleCommand, id *int) error {
 	g.ID = 0
 	return s.Update(r, g, id)
@@ -30,6 +39,9 @@ func (s *SimpleCommandService) Update(r *http.Request, g *SimpleCommand, id *int
 	}
 
 	isNewCmd := g.ID == 0
+	if g.Channel == "" {
+		g.Channel = s.module.bot.DefaultChannel()
+	}
 
 	err := s.module.db.Save(g)
 	if err != nil {
@@ -65,6 +77,10 @@ func (s *SimpleCommandService) Del(r *http.Request, id *int, ret *int) error {
 	return nil
 }
 
-func (s *SimpleCommandService) All(r *http.Request, id *int, g *SimpleCommandList) error {
-	return s.module.db.All(&g.Commands)
+func (s *SimpleCommandService) All(r *http.Request, channel *string, g *SimpleCommandList) error {
+	err := s.module.db.Find("Channel", s.channel(*channel), &g.Commands)
+	if err == storm.ErrNotFound {
+		return nil
+	}
+	return err
 }
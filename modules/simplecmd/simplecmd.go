@@ -1,14 +1,18 @@
-package giveaway
+package simplecmd
 
 import (
+	"sync"
+
 	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
 	"github.com/konkers/roll"
 )
 
 type SimpleCommand struct {
 	ID       int    `json:"id" storm:"id,increment"`
-	Command  string `json: "command"`
-	Response string `json: "response"`
+	Channel  string `json:"channel" storm:"index"`
+	Command  string `json:"command" storm:"index"`
+	Response string `json:"response"`
 }
 
 type SimpleCommandModule struct {
@@ -16,6 +20,11 @@ type SimpleCommandModule struct {
 	db  storm.Node
 
 	service *SimpleCommandService
+
+	// Number of channels that have a command with each name.  The bot
+	// command is registered once and looks up the response per channel.
+	mu     sync.Mutex
+	active map[string]int
 }
 
 func init() {
@@ -24,14 +33,21 @@ func init() {
 
 func NewSimpleCommandModule(bot *roll.Bot, dbBucket storm.Node) (roll.Module, error) {
 	m := &SimpleCommandModule{
-		bot: bot,
-		db:  dbBucket,
+		bot:    bot,
+		db:     dbBucket,
+		active: make(map[string]int),
 	}
 	m.service = NewSimpleCommandService(m)
 
 	var cmds []SimpleCommand
 	m.db.All(&cmds)
 	for _, cmd := range cmds {
+		// Commands saved before multiple channel support belong to the
+		// default channel.
+		if cmd.Channel == "" {
+			cmd.Channel = bot.DefaultChannel()
+			m.db.Save(&cmd)
+		}
 		m.activateCommand(&cmd)
 	}
 
@@ -51,21 +67,42 @@ func (m *SimpleCommandModule) GetRPCService() interface{} {
 }
 
 func (m *SimpleCommandModule) activateCommand(cmd *SimpleCommand) {
-	m.bot.AddCommand(cmd.Command, "Simple Command", func(cc *roll.CommandContext, args []string) error {
-		return m.simpleCommand(cc, cmd.ID, args)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.active[cmd.Command]++
+	if m.active[cmd.Command] > 1 {
+		return
+	}
+
+	name := cmd.Command
+	m.bot.AddModuleCommand(m, name, "Simple Command", func(cc *roll.CommandContext, args []string) error {
+		return m.simpleCommand(cc, name, args)
 	}, 0)
 }
 
 func (m *SimpleCommandModule) deactivateCommand(cmd *SimpleCommand) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.active[cmd.Command]--
+	if m.active[cmd.Command] > 0 {
+		return
+	}
+	delete(m.active, cmd.Command)
 	m.bot.RemoveCommand(cmd.Command)
 }
 
-func (m *SimpleCommandModule) simpleCommand(cc *roll.CommandContext, id int, args []string) error {
+func (m *SimpleCommandModule) simpleCommand(cc *roll.CommandContext, name string, args []string) error {
 	var cmd SimpleCommand
-	err := m.db.One("ID", id, &cmd)
+	err := m.db.Select(q.Eq("Channel", cc.Channel), q.Eq("Command", name)).First(&cmd)
+	if err == storm.ErrNotFound {
+		// This command belongs to a different channel.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	cc.IRC.Say(cc.Channel, cmd.Response)
+	cc.Say(cmd.Response)
 	return nil
 }
@@ -0,0 +1,234 @@
+package roll
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	twitch "github.com/gempir/go-twitch-irc"
+)
+
+// User levels handed to commands.  A command registered with a given level
+// can be run by any user at or above that level.
+const (
+	UserLevelViewer      = 0
+	UserLevelFollower    = 1
+	UserLevelSubscriber  = 5
+	UserLevelVIP         = 8
+	UserLevelModerator   = 10
+	UserLevelBroadcaster = 50
+	UserLevelAdmin       = 100
+)
+
+// UserLevelName returns a readable name for the users at level.
+func UserLevelName(level int) string {
+	switch {
+	case level >= UserLevelAdmin:
+		return "admin"
+	case level >= UserLevelBroadcaster:
+		return "broadcaster"
+	case level >= UserLevelModerator:
+		return "moderators"
+	case level >= UserLevelVIP:
+		return "VIPs"
+	case level >= UserLevelSubscriber:
+		return "subscribers"
+	case level >= UserLevelFollower:
+		return "followers"
+	default:
+		return "everyone"
+	}
+}
+
+// UserLevelOverride pins a user to a level in a channel regardless of
+// their badges.
+type UserLevelOverride struct {
+	ID       int    `json:"id" storm:"id,increment"`
+	Channel  string `json:"channel" storm:"index"`
+	Username string `json:"username" storm:"index"`
+	Level    int    `json:"level"`
+}
+
+// UserArgs names a user in a channel.  An empty channel is the default
+// channel.
+type UserArgs struct {
+	Channel  string `json:"channel"`
+	Username string `json:"username"`
+}
+
+type UserLevelOverrideList struct {
+	Overrides []UserLevelOverride `json:"overrides"`
+}
+
+// UserService exposes user level overrides over rpc.
+type UserService struct {
+	bot *Bot
+}
+
+func NewUserService(bot *Bot) *UserService {
+	return &UserService{
+		bot: bot,
+	}
+}
+
+// badgeUserLevel maps the badges twitch sends with each message onto a
+// user level.
+func badgeUserLevel(user *twitch.User) int {
+	if _, ok := user.Badges["broadcaster"]; ok {
+		return UserLevelBroadcaster
+	}
+	if _, ok := user.Badges["moderator"]; ok || user.UserType == "mod" {
+		return UserLevelModerator
+	}
+	if _, ok := user.Badges["vip"]; ok {
+		return UserLevelVIP
+	}
+	if IsSubscriber(user) {
+		return UserLevelSubscriber
+	}
+	return UserLevelViewer
+}
+
+// IsSubscriber reports whether user's badges show a subscription.
+// Founders, the first subscribers of a channel, wear a founder badge
+// instead of a subscriber one.
+func IsSubscriber(user *twitch.User) bool {
+	if _, ok := user.Badges["subscriber"]; ok {
+		return true
+	}
+	_, ok := user.Badges["founder"]
+	return ok
+}
+
+func (b *Bot) userLevel(channel string, user *twitch.User) int {
+	if user.Username == b.Config.AdminUser {
+		return UserLevelAdmin
+	}
+
+	override, err := b.userLevelOverride(channel, user.Username)
+	if err == nil {
+		return override.Level
+	}
+
+	level := badgeUserLevel(user)
+	if level == UserLevelViewer && b.isFollower(channel, user.UserID) {
+		level = UserLevelFollower
+	}
+	return level
+}
+
+// isFollower reports whether userID follows channel.
+func (b *Bot) isFollower(channel string, userID string) bool {
+	return b.followers.IsFollower(channel, userID)
+}
+
+func (b *Bot) userLevelOverride(channel string, username string) (*UserLevelOverride, error) {
+	var override UserLevelOverride
+	err := b.users.Select(q.Eq("Channel", channel),
+		q.Eq("Username", strings.ToLower(username))).First(&override)
+	if err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// migrateUserLevels moves overrides saved before they were per channel,
+// which were keyed by username, to the default channel.
+func (b *Bot) migrateUserLevels() {
+	var overrides []UserLevelOverride
+	b.users.All(&overrides)
+	for _, o := range overrides {
+		if o.Channel != "" {
+			continue
+		}
+		b.users.Delete("UserLevelOverride", o.Username)
+		err := b.SetUserLevel(b.DefaultChannel(), o.Username, o.Level)
+		if err != nil {
+			log.Printf("Can't migrate user level of %s: %v", o.Username, err)
+		}
+	}
+}
+
+// SetUserLevel overrides the level of username in channel.
+func (b *Bot) SetUserLevel(channel string, username string, level int) error {
+	if username == "" {
+		return fmt.Errorf("no username given")
+	}
+	override, err := b.userLevelOverride(channel, username)
+	if err == storm.ErrNotFound {
+		override = &UserLevelOverride{
+			Channel:  channel,
+			Username: strings.ToLower(username),
+		}
+	} else if err != nil {
+		return err
+	}
+	override.Level = level
+	return b.users.Save(override)
+}
+
+// ClearUserLevel removes username's level override in channel.
+func (b *Bot) ClearUserLevel(channel string, username string) error {
+	override, err := b.userLevelOverride(channel, username)
+	if err != nil {
+		return err
+	}
+	return b.users.DeleteStruct(override)
+}
+
+func (s *UserService) channel(c string) string {
+	if c == "" {
+		return s.bot.DefaultChannel()
+	}
+	return c
+}
+
+func (s *UserService) Set(r *http.Request, override *UserLevelOverride, ret *int) error {
+	if !s.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.bot.SetUserLevel(s.channel(override.Channel), override.Username, override.Level)
+	if err != nil {
+		return err
+	}
+	*ret = override.Level
+	return nil
+}
+
+func (s *UserService) Get(r *http.Request, args *UserArgs, override *UserLevelOverride) error {
+	if !s.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	o, err := s.bot.userLevelOverride(s.channel(args.Channel), args.Username)
+	if err != nil {
+		return err
+	}
+	*override = *o
+	return nil
+}
+
+func (s *UserService) Del(r *http.Request, args *UserArgs, ret *string) error {
+	if !s.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.bot.ClearUserLevel(s.channel(args.Channel), args.Username)
+	if err != nil {
+		return err
+	}
+	*ret = args.Username
+	return nil
+}
+
+func (s *UserService) All(r *http.Request, channel *string, list *UserLevelOverrideList) error {
+	if !s.bot.IsAdminRequest(r) {
+		return fmt.Errorf("access denied")
+	}
+	err := s.bot.users.Find("Channel", s.channel(*channel), &list.Overrides)
+	if err == storm.ErrNotFound {
+		return nil
+	}
+	return err
+}
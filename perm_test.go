@@ -0,0 +1,92 @@
+package roll
+
+import (
+	"testing"
+
+	twitch "github.com/gempir/go-twitch-irc"
+)
+
+func TestBadgeUserLevel(t *testing.T) {
+	tests := []struct {
+		user  twitch.User
+		level int
+	}{
+		{twitch.User{}, UserLevelViewer},
+		{twitch.User{Badges: map[string]int{"subscriber": 12}}, UserLevelSubscriber},
+		{twitch.User{Badges: map[string]int{"founder": 0}}, UserLevelSubscriber},
+		{twitch.User{Badges: map[string]int{"vip": 1, "subscriber": 3}}, UserLevelVIP},
+		{twitch.User{Badges: map[string]int{"moderator": 1, "vip": 1}}, UserLevelModerator},
+		{twitch.User{UserType: "mod"}, UserLevelModerator},
+		{twitch.User{Badges: map[string]int{"broadcaster": 1, "subscriber": 0}}, UserLevelBroadcaster},
+	}
+
+	for _, test := range tests {
+		level := badgeUserLevel(&test.user)
+		if level != test.level {
+			t.Errorf("badgeUserLevel(%v) returned %d instead of %d",
+				test.user.Badges, level, test.level)
+		}
+	}
+}
+
+func TestUserLevelOverride(t *testing.T) {
+	b, _ := newTestBot(t)
+	user := &twitch.User{
+		Username: "someone",
+		Badges:   map[string]int{"subscriber": 1},
+	}
+
+	err := b.SetUserLevel("testchan", "SomeOne", UserLevelModerator)
+	if err != nil {
+		t.Fatalf("Can't set user level: %v", err)
+	}
+
+	level := b.userLevel("testchan", user)
+	if level != UserLevelModerator {
+		t.Errorf("Overridden user level %d != %d", level, UserLevelModerator)
+	}
+	level = b.userLevel("otherchan", user)
+	if level != UserLevelSubscriber {
+		t.Errorf("Override in testchan gives level %d in otherchan", level)
+	}
+
+	var override UserLevelOverride
+	args := &UserArgs{Channel: "testchan", Username: "someone"}
+	err = b.userService.Get(nil, args, &override)
+	if err != nil {
+		t.Errorf("Can't get override over rpc: %v", err)
+	}
+	if override.Level != UserLevelModerator {
+		t.Errorf("rpc override level %d != %d", override.Level, UserLevelModerator)
+	}
+
+	err = b.SetUserLevel("otherchan", "someoneelse", UserLevelVIP)
+	if err != nil {
+		t.Fatalf("Can't set user level: %v", err)
+	}
+	for _, channel := range []string{"", "testchan", "otherchan"} {
+		var list UserLevelOverrideList
+		err = b.userService.All(nil, &channel, &list)
+		if err != nil {
+			t.Errorf("Can't list overrides of %q over rpc: %v", channel, err)
+		}
+		want := "someone"
+		if channel == "otherchan" {
+			want = "someoneelse"
+		}
+		if len(list.Overrides) != 1 || list.Overrides[0].Username != want {
+			t.Errorf("Overrides of %q are %v instead of just %s", channel, list.Overrides, want)
+		}
+	}
+
+	var ret string
+	err = b.userService.Del(nil, args, &ret)
+	if err != nil {
+		t.Fatalf("Can't delete override: %v", err)
+	}
+
+	level = b.userLevel("testchan", user)
+	if level != UserLevelSubscriber {
+		t.Errorf("User level %d != %d after clearing override", level, UserLevelSubscriber)
+	}
+}
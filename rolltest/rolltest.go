@@ -0,0 +1,59 @@
+// Package rolltest creates bots for testing modules.
+package rolltest
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/konkers/roll"
+)
+
+// Channels the test bot is configured for.  All modules are enabled in
+// both.
+const (
+	Channel      = "testchan"
+	OtherChannel = "otherchan"
+)
+
+// NewBot returns a bot with an empty temporary database that tells time
+// with clock.  The bot isn't connected, so nothing it says is sent;
+// Bot.TakeMessages returns it instead.
+func NewBot(t *testing.T, clock roll.Clock) *roll.Bot {
+	tmpFile, err := ioutil.TempFile("", "bot.*.db")
+	if err != nil {
+		t.Fatalf("Can't get temporary file: %v", err)
+	}
+	tmpFile.Close()
+
+	b, err := roll.NewBotWithClock(&roll.Config{
+		BotUsername: "RollTheRobot",
+		Channels: []roll.ChannelConfig{
+			{Name: Channel},
+			{Name: OtherChannel},
+		},
+		AdminUser: "rock",
+		DBPath:    tmpFile.Name(),
+	}, clock)
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		t.Fatalf("NewBotWithClock() returned error: %v", err)
+	}
+	return b
+}
+
+// Close closes b and removes its database.
+func Close(b *roll.Bot) {
+	b.Close()
+	os.Remove(b.Config.DBPath)
+}
+
+// AddModule adds module modType to b and returns it.
+func AddModule(t *testing.T, b *roll.Bot, modType string) roll.Module {
+	err := b.AddModule(modType)
+	if err != nil {
+		t.Fatalf("AddModule(%s) returned error: %v", modType, err)
+	}
+	m, _ := b.GetModule(modType)
+	return m
+}
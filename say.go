@@ -0,0 +1,325 @@
+package roll
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	twitch "github.com/gempir/go-twitch-irc"
+	"github.com/konkers/roll/clock"
+)
+
+// Priority orders outgoing chat messages.  Higher priority messages are
+// sent first.
+type Priority int
+
+const (
+	PriorityLow = Priority(iota)
+	PriorityNormal
+	PriorityHigh
+	numPriorities
+)
+
+const (
+	// Longest message twitch accepts.
+	maxMessageLength = 500
+
+	// Twitch allows 20 messages per 30 seconds, or 100 in channels where
+	// the bot is a moderator.
+	rateWindow         = 30 * time.Second
+	rateLimitUser      = 20
+	rateLimitModerator = 100
+
+	mergeSeparator = " | "
+)
+
+type outMessage struct {
+	channel  string
+	user     string // Set for whispers.
+	text     string
+	priority Priority
+}
+
+// messageQueue holds outgoing messages ordered by priority.
+type messageQueue struct {
+	mu     sync.Mutex
+	queues [numPriorities][]*outMessage
+	wakeC  chan struct{}
+}
+
+// rateLimiter tracks messages sent in a sliding window.
+type rateLimiter struct {
+	window time.Duration
+	sent   []time.Time
+}
+
+func newMessageQueue() *messageQueue {
+	return &messageQueue{
+		wakeC: make(chan struct{}, 1),
+	}
+}
+
+// push queues msg, splitting it if it's over the length limit.
+func (q *messageQueue) push(msg *outMessage) {
+	if msg.priority < PriorityLow {
+		msg.priority = PriorityLow
+	} else if msg.priority >= numPriorities {
+		msg.priority = numPriorities - 1
+	}
+
+	q.mu.Lock()
+	for _, text := range splitMessage(msg.text, maxMessageLength) {
+		q.queues[msg.priority] = append(q.queues[msg.priority], &outMessage{
+			channel:  msg.channel,
+			user:     msg.user,
+			text:     text,
+			priority: msg.priority,
+		})
+	}
+	q.mu.Unlock()
+	q.wake()
+}
+
+// wake tells the send worker to look at the queue again.
+func (q *messageQueue) wake() {
+	select {
+	case q.wakeC <- struct{}{}:
+	default:
+	}
+}
+
+// peek returns the next message to be sent without removing it.
+func (q *messageQueue) peek() *outMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for p := numPriorities - 1; p >= PriorityLow; p-- {
+		if len(q.queues[p]) > 0 {
+			return q.queues[p][0]
+		}
+	}
+	return nil
+}
+
+// isChatCommand reports whether text is a chat command like /me or .timeout
+// rather than a message.  Chat commands are never merged.
+func isChatCommand(text string) bool {
+	return strings.HasPrefix(text, "/") || strings.HasPrefix(text, ".")
+}
+
+// pop removes and returns the next message to be sent.  Following messages
+// to the same channel or user are merged into it while they fit in a single line.
+func (q *messageQueue) pop() *outMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for p := numPriorities - 1; p >= PriorityLow; p-- {
+		queue := q.queues[p]
+		if len(queue) == 0 {
+			continue
+		}
+
+		msg := *queue[0]
+		queue = queue[1:]
+		for len(queue) > 0 && queue[0].channel == msg.channel && queue[0].user == msg.user &&
+			!isChatCommand(msg.text) && !isChatCommand(queue[0].text) &&
+			len(msg.text)+len(mergeSeparator)+len(queue[0].text) <= maxMessageLength {
+			msg.text += mergeSeparator + queue[0].text
+			queue = queue[1:]
+		}
+		q.queues[p] = queue
+		return &msg
+	}
+	return nil
+}
+
+// take removes and returns the text of every message queued for channel,
+// in the order they would be sent, without merging them.
+func (q *messageQueue) take(channel string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var texts []string
+	for p := numPriorities - 1; p >= PriorityLow; p-- {
+		var kept []*outMessage
+		for _, msg := range q.queues[p] {
+			if msg.channel == channel && msg.user == "" {
+				texts = append(texts, msg.text)
+			} else {
+				kept = append(kept, msg)
+			}
+		}
+		q.queues[p] = kept
+	}
+	return texts
+}
+
+func (q *messageQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, queue := range q.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// delay returns how long to wait before a message can be sent without
+// exceeding limit messages per window.
+func (l *rateLimiter) delay(now time.Time, limit int) time.Duration {
+	// Forget messages that have left the window.
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.sent) && !l.sent[i].After(cutoff) {
+		i++
+	}
+	l.sent = l.sent[i:]
+
+	if len(l.sent) < limit {
+		return 0
+	}
+	return l.sent[len(l.sent)-limit].Add(l.window).Sub(now)
+}
+
+func (l *rateLimiter) record(now time.Time) {
+	l.sent = append(l.sent, now)
+}
+
+// splitMessage breaks text into lines of at most max characters, preferring
+// to break between words.
+func splitMessage(text string, max int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > max {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		for len(w) > max {
+			lines = append(lines, string(w[:max]))
+			w = w[max:]
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// Say queues text to be said in channel.
+func (b *Bot) Say(channel string, text string) {
+	b.SayPriority(channel, text, PriorityNormal)
+}
+
+// SayPriority queues text to be said in channel with the given priority.
+// Messages are sent as fast as twitch's rate limits allow.
+func (b *Bot) SayPriority(channel string, text string, priority Priority) {
+	b.outQ.push(&outMessage{
+		channel:  channel,
+		text:     text,
+		priority: priority,
+	})
+}
+
+// TakeMessages removes and returns the messages queued for channel that
+// haven't been sent.  It lets tests see what a bot that isn't connected
+// said.
+func (b *Bot) TakeMessages(channel string) []string {
+	return b.outQ.take(channel)
+}
+
+// Whisper queues text to be whispered to user.
+func (b *Bot) Whisper(user string, text string) {
+	b.outQ.push(&outMessage{
+		user:     user,
+		text:     text,
+		priority: PriorityNormal,
+	})
+}
+
+// Say queues text to be said in the channel the command came from.
+func (cc *CommandContext) Say(text string) {
+	cc.Bot.Say(cc.Channel, text)
+}
+
+// Whisper queues text to be whispered to the user that ran the command.
+func (cc *CommandContext) Whisper(text string) {
+	cc.Bot.Whisper(cc.User.Username, text)
+}
+
+func (b *Bot) isModerator(channel string) bool {
+	b.modMu.Lock()
+	defer b.modMu.Unlock()
+	return b.modChannels[channel]
+}
+
+// handleUserstate tracks which channels the bot is a moderator in.  Twitch
+// sends a USERSTATE for the bot on joining a channel and after it speaks.
+func (b *Bot) handleUserstate(channel string, user twitch.User, message twitch.Message) {
+	level := badgeUserLevel(&user)
+	b.modMu.Lock()
+	b.modChannels[channel] = level >= UserLevelModerator
+	b.modMu.Unlock()
+}
+
+// sendWorker sends queued messages until sendStopC is closed.  It then
+// keeps sending for up to sendFlushTimeout to flush the queue.
+func (b *Bot) sendWorker() {
+	defer close(b.sendDone)
+
+	stopC := b.sendStopC
+	var flushC <-chan time.Time // Set once stopping.
+	for {
+		msg := b.outQ.peek()
+		if msg == nil || b.ConnectionState() != ConnectionConnected {
+			if flushC != nil {
+				b.dropUnsent()
+				return
+			}
+			select {
+			case <-b.outQ.wakeC:
+			case <-stopC:
+				stopC = nil
+				flushC = clock.After(b.clock, sendFlushTimeout)
+			}
+			continue
+		}
+
+		limit := rateLimitUser
+		if b.isModerator(msg.channel) {
+			limit = rateLimitModerator
+		}
+		if d := b.limiter.delay(b.clock.Now(), limit); d > 0 {
+			select {
+			case <-clock.After(b.clock, d):
+			case <-stopC:
+				stopC = nil
+				flushC = clock.After(b.clock, sendFlushTimeout)
+			case <-flushC:
+				b.dropUnsent()
+				return
+			}
+			continue
+		}
+
+		msg = b.outQ.pop()
+		if msg == nil {
+			continue
+		}
+		b.limiter.record(b.clock.Now())
+		if msg.user != "" {
+			b.ircClient.Whisper(msg.user, msg.text)
+		} else {
+			b.ircClient.Say(msg.channel, msg.text)
+		}
+	}
+}
+
+func (b *Bot) dropUnsent() {
+	if n := b.outQ.len(); n > 0 {
+		log.Printf("Dropping %d unsent messages.", n)
+	}
+}
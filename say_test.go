@@ -0,0 +1,140 @@
+package roll
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		text  string
+		max   int
+		lines []string
+	}{
+		{"", 10, nil},
+		{"hello", 10, []string{"hello"}},
+		{"hello world", 11, []string{"hello world"}},
+		{"hello world", 10, []string{"hello", "world"}},
+		{"a  b   c", 10, []string{"a b c"}},
+		{"abcdefghijkl", 5, []string{"abcde", "fghij", "kl"}},
+		{"ab abcdefghijkl", 5, []string{"ab", "abcde", "fghij", "kl"}},
+		{"ééé ééé", 3, []string{"ééé", "ééé"}},
+	}
+
+	for _, test := range tests {
+		lines := splitMessage(test.text, test.max)
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("splitMessage(\"%s\", %d) returned %q instead of %q",
+				test.text, test.max, lines, test.lines)
+		}
+	}
+}
+
+func TestMessageQueuePriority(t *testing.T) {
+	q := newMessageQueue()
+	q.push(&outMessage{channel: "a", text: "low", priority: PriorityLow})
+	q.push(&outMessage{channel: "b", text: "normal", priority: PriorityNormal})
+	q.push(&outMessage{channel: "c", text: "high", priority: PriorityHigh})
+
+	if q.len() != 3 {
+		t.Errorf("Queue has %d messages instead of 3", q.len())
+	}
+
+	for _, expected := range []string{"high", "normal", "low"} {
+		if q.peek().text != expected {
+			t.Errorf("peek() returned %s instead of %s", q.peek().text, expected)
+		}
+		msg := q.pop()
+		if msg.text != expected {
+			t.Errorf("pop() returned %s instead of %s", msg.text, expected)
+		}
+	}
+
+	if q.pop() != nil {
+		t.Errorf("pop() of an empty queue returned a message")
+	}
+}
+
+func TestMessageQueueMerge(t *testing.T) {
+	q := newMessageQueue()
+	q.push(&outMessage{channel: "a", text: "one", priority: PriorityNormal})
+	q.push(&outMessage{channel: "a", text: "two", priority: PriorityNormal})
+	q.push(&outMessage{channel: "b", text: "three", priority: PriorityNormal})
+	q.push(&outMessage{channel: "b", text: strings.Repeat("x", maxMessageLength), priority: PriorityNormal})
+
+	expected := []string{"one | two", "three", strings.Repeat("x", maxMessageLength)}
+	for _, text := range expected {
+		msg := q.pop()
+		if msg == nil || msg.text != text {
+			t.Fatalf("pop() returned %v instead of %s", msg, text)
+		}
+	}
+}
+
+func TestMessageQueueMergeChatCommands(t *testing.T) {
+	q := newMessageQueue()
+	for _, text := range []string{"one", "/me waves", "two", ".timeout someone 60", "three", "four"} {
+		q.push(&outMessage{channel: "a", text: text, priority: PriorityNormal})
+	}
+
+	expected := []string{"one", "/me waves", "two", ".timeout someone 60", "three | four"}
+	for _, text := range expected {
+		msg := q.pop()
+		if msg == nil || msg.text != text {
+			t.Fatalf("pop() returned %v instead of %s", msg, text)
+		}
+	}
+}
+
+func TestMessageQueueTake(t *testing.T) {
+	q := newMessageQueue()
+	q.push(&outMessage{channel: "a", text: "one", priority: PriorityNormal})
+	q.push(&outMessage{channel: "b", text: "other", priority: PriorityNormal})
+	q.push(&outMessage{user: "a", text: "whisper", priority: PriorityHigh})
+	q.push(&outMessage{channel: "a", text: "two", priority: PriorityNormal})
+	q.push(&outMessage{channel: "a", text: "urgent", priority: PriorityHigh})
+
+	texts := q.take("a")
+	expected := []string{"urgent", "one", "two"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("take() returned %q instead of %q", texts, expected)
+	}
+	if q.len() != 2 {
+		t.Errorf("take() left %d messages instead of 2", q.len())
+	}
+	if texts := q.take("a"); texts != nil {
+		t.Errorf("Second take() returned %q", texts)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{window: 30 * time.Second}
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		if d := l.delay(now, 3); d != 0 {
+			t.Errorf("Message %d delayed by %v", i, d)
+		}
+		l.record(now)
+	}
+
+	now := start.Add(10 * time.Second)
+	if d := l.delay(now, 3); d != 20*time.Second {
+		t.Errorf("Message over the limit delayed by %v instead of 20s", d)
+	}
+
+	if d := l.delay(now, 5); d != 0 {
+		t.Errorf("Message under a higher limit delayed by %v", d)
+	}
+
+	now = start.Add(30 * time.Second)
+	if d := l.delay(now, 3); d != 0 {
+		t.Errorf("Message after the window delayed by %v", d)
+	}
+	if len(l.sent) != 2 {
+		t.Errorf("Rate limiter remembers %d messages instead of 2", len(l.sent))
+	}
+}
@@ -0,0 +1,61 @@
+package roll
+
+import (
+	"encoding/json"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// How long a channel's online status is cached.
+const streamStatusTTL = 1 * time.Minute
+
+type streamStatus struct {
+	online  bool
+	checked time.Time
+}
+
+type streamCache struct {
+	mu       sync.Mutex
+	statuses map[string]streamStatus
+}
+
+// StreamOnline reports whether channel is live.  Results are cached for
+// streamStatusTTL.
+func (b *Bot) StreamOnline(channel string) (bool, error) {
+	b.streams.mu.Lock()
+	status, ok := b.streams.statuses[channel]
+	b.streams.mu.Unlock()
+	if ok && b.clock.Now().Sub(status.checked) < streamStatusTTL {
+		return status.online, nil
+	}
+
+	var resp struct {
+		Stream json.RawMessage `json:"stream"`
+	}
+	err := b.apiGet("/streams/"+url.PathEscape(channel), nil, &resp)
+	if err != nil {
+		return false, err
+	}
+	status = streamStatus{
+		online:  len(resp.Stream) > 0 && string(resp.Stream) != "null",
+		checked: b.clock.Now(),
+	}
+
+	b.streams.mu.Lock()
+	b.streams.statuses[channel] = status
+	b.streams.mu.Unlock()
+	return status.online, nil
+}
+
+// ChannelGame returns the game channel is set to.
+func (b *Bot) ChannelGame(channel string) (string, error) {
+	var resp struct {
+		Game string `json:"game"`
+	}
+	err := b.apiGet("/channels/"+url.PathEscape(channel), nil, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.Game, nil
+}
@@ -1,10 +1,12 @@
 package roll
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
@@ -76,6 +78,16 @@ func (b *Bot) getTemplate(filename string) (*template.Template, error) {
 	return t, nil
 }
 
+// ExecuteTemplate renders the template filename with data to w.  Modules use
+// this to render their pages with the bot's template functions.
+func (b *Bot) ExecuteTemplate(w io.Writer, filename string, data interface{}) error {
+	t, err := b.getTemplate(filename)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
+
 func (b *Bot) AddTemplateFunc(name string, f interface{}) error {
 	if _, ok := b.funcMap[name]; ok {
 		return fmt.Errorf("%s template func already registered", name)
@@ -107,17 +119,30 @@ func (b *Bot) redirectHandler(w http.ResponseWriter, req *http.Request) {
 	http.Redirect(w, req, target, http.StatusTemporaryRedirect)
 }
 
+// adminOnly rejects requests to h that aren't from an admin.
+func (b *Bot) adminOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !b.IsAdminRequest(req) {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
 func (b *Bot) startWebserver() error {
 	r := mux.NewRouter()
 
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
 	r.HandleFunc("/auth/", b.authHandler)
 	r.HandleFunc("/wiki/{page}", b.wikiHandler)
+	r.HandleFunc("/commands", b.commandsHandler)
 	r.HandleFunc("/", b.indexHandler)
 
 	s := rpc.NewServer()
 	s.RegisterCodec(rpcjson.NewCodec(), "application/json")
 
+	s.RegisterService(b.userService, "users")
 	for name, mod := range b.modules {
 		if provider, ok := mod.(RPCServiceProvider); ok {
 			s.RegisterService(provider.GetRPCService(), name)
@@ -125,6 +150,19 @@ func (b *Bot) startWebserver() error {
 	}
 	r.Handle("/rpc", s)
 
+	for name, mod := range b.modules {
+		if provider, ok := mod.(PublicWebProvider); ok {
+			prefix := "/m/" + name
+			r.PathPrefix(prefix + "/").Handler(
+				http.StripPrefix(prefix, provider.GetPublicHandler()))
+		}
+		if provider, ok := mod.(AdminWebProvider); ok {
+			prefix := "/admin/" + name
+			r.PathPrefix(prefix + "/").Handler(
+				http.StripPrefix(prefix, b.adminOnly(provider.GetAdminHandler())))
+		}
+	}
+
 	cert, err := tls.LoadX509KeyPair(b.Config.CertFile, b.Config.KeyFile)
 	if err != nil {
 		return err
@@ -138,7 +176,8 @@ func (b *Bot) startWebserver() error {
 		return err
 	}
 
-	go http.Serve(listener, r)
+	b.httpsServer = &http.Server{Handler: r}
+	go b.httpsServer.Serve(listener)
 
 	if b.Config.HTTPAddr != "" {
 		log.Printf("Starting HTTP redirector on http://%s/", b.Config.HTTPAddr)
@@ -147,8 +186,24 @@ func (b *Bot) startWebserver() error {
 			return err
 		}
 
-		go http.Serve(listener, http.HandlerFunc(b.redirectHandler))
+		b.httpServer = &http.Server{Handler: http.HandlerFunc(b.redirectHandler)}
+		go b.httpServer.Serve(listener)
 	}
 
 	return nil
 }
+
+// stopWebserver gracefully shuts down any running web servers.
+func (b *Bot) stopWebserver(ctx context.Context) {
+	for _, server := range []*http.Server{b.httpsServer, b.httpServer} {
+		if server == nil {
+			continue
+		}
+		err := server.Shutdown(ctx)
+		if err != nil {
+			log.Printf("Error shutting down web server: %v", err)
+		}
+	}
+	b.httpsServer = nil
+	b.httpServer = nil
+}
@@ -3,6 +3,7 @@ package roll
 import (
 	"crypto/tls"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"testing"
 
@@ -219,3 +220,83 @@ func TestIndex(t *testing.T) {
 		t.Errorf("Got error getting %s: %v", url, err)
 	}
 }
+
+type TestWebModule struct{}
+
+func (m *TestWebModule) Start() error {
+	return nil
+}
+
+func (m *TestWebModule) Stop() error {
+	return nil
+}
+
+func (m *TestWebModule) GetPublicHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+}
+
+func (m *TestWebModule) GetAdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin" + r.URL.Path))
+	})
+}
+
+func TestWebPublicProvider(t *testing.T) {
+	err := RegisterModuleFactory(func(bot *Bot, dbBucket storm.Node) (Module, error) {
+		return &TestWebModule{}, nil
+	}, "test_web")
+	if err != nil {
+		t.Fatalf("Unexpected error from RegisterModuleFactory(): %v", err)
+	}
+
+	bot, _ := newTestBot(t)
+	err = bot.AddModule("test_web")
+	if err != nil {
+		t.Fatalf("Unexpected error from AddModule(): %v", err)
+	}
+
+	err = bot.Connect()
+	if err != nil {
+		t.Fatalf("Unexpected error from bot.Connect(): %v", err)
+	}
+
+	client := getTestHttpClient()
+	url := "https://" + bot.Config.HTTPSAddr + "/m/test_web/page"
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Got error getting %s: %v", url, err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Can't read body of %s: %v", url, err)
+	}
+	if string(body) != "/page" {
+		t.Errorf("Module handler saw path \"%s\" instead of \"/page\"", string(body))
+	}
+
+	url = "https://" + bot.Config.HTTPSAddr + "/admin/test_web/page"
+	resp, err = client.Get(url)
+	if err != nil {
+		t.Fatalf("Got error getting %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Unauthenticated admin request returned %s", resp.Status)
+	}
+
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Client-ID", bot.Config.ClientID)
+	req.Header.Set("Authorization", "OAuth "+bot.Config.APIOAuth)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Got error getting %s: %v", url, err)
+	}
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Can't read body of %s: %v", url, err)
+	}
+	if string(body) != "admin/page" {
+		t.Errorf("Admin handler saw path \"%s\" instead of \"/page\"", string(body))
+	}
+}